@@ -9,7 +9,11 @@ type Arg struct {
 	DictOption
 	SpeedOption
 	ProxyOption
-	Node     bool `arg:"-N,--node" help:"Start in node mode"`
-	NodePort int  `arg:"--node-port" help:"Port number of node mode [default: Random]"`
-	Version  bool `arg:"-V,--" help:"display version and exit"`
+	SecurityOption
+	Node      bool     `arg:"-N,--node" help:"Start in node mode"`
+	NodePort  int      `arg:"--node-port" help:"Port number of node mode [default: Random]"`
+	Nodes     []string `arg:"--nodes" help:"Distribute the scan across these nodes (host:port)" validate:"omitempty,unique,dive,hostname_port" errMsg:"invalid node address"`
+	Serve     string   `arg:"--serve" placeholder:"ADDR" help:"Serve the proxies as a local SOCKS5/HTTP proxy on ADDR (host:port)"`
+	ServeAuth string   `arg:"--serve-auth" placeholder:"USER:PASS" help:"Login required by --serve, needed unless ADDR is a loopback address"`
+	Version   bool     `arg:"-V,--" help:"display version and exit"`
 }
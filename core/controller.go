@@ -0,0 +1,388 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"r4scan/core/local"
+	"r4scan/generator"
+	"r4scan/http"
+	"sync"
+)
+
+// Controller splits the dictionary of a scan into shards and hands them out
+// to a set of nodes. A shard left unfinished by a failing node is given to
+// another node, and the streamed results are merged into a single report.
+type Controller struct {
+	nodes       []string
+	shardSize   int
+	dialOptions []grpc.DialOption
+	logger      func(result *http.CheckResult)
+}
+
+type NodeReport struct {
+	Addr   string
+	Shards int
+	Hits   int
+	Err    error
+}
+
+type Report struct {
+	Hits  int
+	Nodes []*NodeReport
+}
+
+type shard struct {
+	id    int
+	paths []string
+}
+
+// tracker closes done once every produced shard has finished
+type tracker struct {
+	mu          sync.Mutex
+	outstanding int
+	produced    bool
+	done        chan struct{}
+}
+
+func NewController(nodes []string) *Controller {
+	return &Controller{
+		nodes:     nodes,
+		shardSize: 1000,
+		dialOptions: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		},
+	}
+}
+
+func (c *Controller) SetShardSize(size int) *Controller {
+
+	if size > 0 {
+		c.shardSize = size
+	}
+	return c
+}
+
+func (c *Controller) SetDialOptions(options ...grpc.DialOption) *Controller {
+
+	c.dialOptions = options
+	return c
+}
+
+// SetLogger receives the proxies dropped by the proxy check
+func (c *Controller) SetLogger(logger func(result *http.CheckResult)) *Controller {
+
+	c.logger = logger
+	return c
+}
+
+func (c *Controller) Run(ctx context.Context, request *local.CreateRequest, handler func(node string, reply *local.CreateReply)) (*Report, error) {
+
+	if len(c.nodes) == 0 {
+		return nil, fmt.Errorf("controller: no node specified")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gen := generator.NewGenerator(request.DictPath).
+		SetDict(request.Dict).
+		SetComment(request.DictComment).
+		SetPath(request.Path).
+		SetExt(request.Ext).
+		SetVariable(request.Variable)
+
+	paths, err := gen.Paths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pending = make(chan *shard)
+		retry   = make(chan *shard, len(c.nodes))
+		track   = &tracker{done: make(chan struct{})}
+		report  = &Report{}
+		merged  = map[string]struct{}{}
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		fatal   error
+	)
+
+	//the rate limit is global, every node gets its share
+	job := proto.Clone(request).(*local.CreateRequest)
+	job.Dict = nil
+	job.DictPath = ""
+	job.Ext = nil
+	job.Variable = nil
+	if job.MaxSpeed > 0 {
+		job.MaxSpeed = (job.MaxSpeed + int32(len(c.nodes)) - 1) / int32(len(c.nodes))
+	}
+
+	//every shard is a new job on the node, check the proxies once here and
+	//hand out the survivors only
+	if job.ProxyCheck && len(job.Proxy) > 0 {
+		var dead []*http.CheckResult
+		job.Proxy, dead, err = checkedProxies(job)
+		if c.logger != nil {
+			for _, result := range dead {
+				c.logger(result)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		job.ProxyCheck = false
+	}
+
+	go func() {
+
+		defer close(pending)
+		defer track.finishProducing()
+
+		var (
+			id    int
+			batch = make([]string, 0, c.shardSize)
+		)
+
+		flush := func() bool {
+			track.add()
+			select {
+			case pending <- &shard{id: id, paths: batch}:
+				id++
+				batch = make([]string, 0, c.shardSize)
+				return true
+			case <-ctx.Done():
+				track.finish()
+				return false
+			}
+		}
+
+		for path := range paths {
+			if batch = append(batch, path); len(batch) == c.shardSize && !flush() {
+				return
+			}
+		}
+
+		if len(batch) > 0 {
+			flush()
+		}
+	}()
+
+	merge := func(node *NodeReport, reply *local.CreateReply) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		//a reassigned shard may report a hit twice
+		if _, exist := merged[reply.Url]; exist {
+			return
+		}
+		merged[reply.Url] = struct{}{}
+
+		node.Hits++
+		report.Hits++
+		handler(node.Addr, reply)
+	}
+
+	alive := len(c.nodes)
+
+	for _, addr := range c.nodes {
+
+		node := &NodeReport{Addr: addr}
+		report.Nodes = append(report.Nodes, node)
+
+		wg.Add(1)
+		go func() {
+
+			defer wg.Done()
+
+			err := c.worker(ctx, node, job, pending, retry, track, merge)
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			node.Err = err
+			alive--
+
+			if status.Code(err) == codes.InvalidArgument {
+				//the job itself is rejected, no other node will accept it
+				if fatal == nil {
+					fatal = err
+				}
+				cancel()
+			} else if alive == 0 {
+				if fatal == nil {
+					fatal = fmt.Errorf("controller: all nodes failed, last error: %v", err)
+				}
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	select {
+	case <-track.done:
+	default:
+		if fatal != nil {
+			return report, fatal
+		}
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	if err = gen.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (c *Controller) worker(ctx context.Context, node *NodeReport, job *local.CreateRequest, pending <-chan *shard, retry chan *shard, track *tracker, merge func(node *NodeReport, reply *local.CreateReply)) error {
+
+	conn, err := grpc.DialContext(ctx, node.Addr, c.dialOptions...)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := local.NewR4ScanClient(conn)
+
+	for {
+
+		var (
+			s  *shard
+			ok bool
+		)
+
+		//unfinished shards of failed nodes go first
+		select {
+		case s = <-retry:
+		default:
+			select {
+			case s = <-retry:
+			case s, ok = <-pending:
+				if !ok {
+					pending = nil
+					continue
+				}
+			case <-track.done:
+				return nil
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		if err = c.runShard(ctx, client, node, job, s, merge); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			retry <- s
+			return err
+		}
+
+		node.Shards++
+		track.finish()
+	}
+}
+
+func (c *Controller) runShard(ctx context.Context, client local.R4ScanClient, node *NodeReport, job *local.CreateRequest, s *shard, merge func(node *NodeReport, reply *local.CreateReply)) error {
+
+	request := proto.Clone(job).(*local.CreateRequest)
+	request.Path = s.paths
+
+	stream, err := client.Create(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		merge(node, reply)
+	}
+}
+
+// checkedProxies returns the links of the proxies passing the check of
+// request, fastest first, and the failing proxies as dead
+func checkedProxies(request *local.CreateRequest) (links []string, dead []*http.CheckResult, err error) {
+
+	chain, err := NewProxyChain(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if chain != nil {
+		defer chain.Close()
+	}
+
+	var proxies []*http.Proxy
+
+	for _, rawUrl := range request.Proxy {
+		proxy, err := NewProxy(request, rawUrl)
+		if err != nil {
+			closeProxies(proxies)
+			return nil, nil, fmt.Errorf("invalid proxy \"%s\": %v", rawUrl, err)
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	alive, dead, err := CheckProxies(request, proxies, chain)
+	if err != nil {
+		return nil, dead, err
+	}
+
+	for _, result := range alive {
+		links = append(links, result.Proxy.Raw)
+		_ = result.Proxy.Close()
+	}
+
+	return links, dead, nil
+}
+
+func (t *tracker) add() {
+
+	t.mu.Lock()
+	t.outstanding++
+	t.mu.Unlock()
+}
+
+func (t *tracker) finish() {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.outstanding--
+	t.check()
+}
+
+func (t *tracker) finishProducing() {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.produced = true
+	t.check()
+}
+
+func (t *tracker) check() {
+
+	if t.produced && t.outstanding == 0 {
+		close(t.done)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"google.golang.org/grpc"
+	"net"
+	"r4scan/core/local"
+	"r4scan/http"
+	"r4scan/util"
+)
+
+type Server struct {
+	local.UnimplementedR4ScanServer
+	logger func(result *http.CheckResult)
+	node   bool
+}
+
+// RunAsLocal starts a scan service on a random loopback port for the
+// current process and returns its address. logger receives the proxies
+// dropped by the proxy check.
+func RunAsLocal(logger func(result *http.CheckResult)) (*grpc.Server, string, error) {
+
+	port, err := util.GetAvailablePort()
+	if err != nil {
+		return nil, "", err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, "", err
+	}
+
+	server := newServer(&Server{logger: logger})
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return server, listener.Addr().String(), nil
+}
+
+// RunAsNode serves scan jobs from remote controllers until the listener
+// fails. A random port is used when port is 0.
+func RunAsNode(port int, security *Security, ready func(addr string), logger func(result *http.CheckResult)) error {
+
+	options, err := security.ServerOptions()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	if ready != nil {
+		ready(listener.Addr().String())
+	}
+
+	return newServer(&Server{logger: logger, node: true}, options...).Serve(listener)
+}
+
+func newServer(service *Server, options ...grpc.ServerOption) *grpc.Server {
+
+	server := grpc.NewServer(options...)
+	local.RegisterR4ScanServer(server, service)
+
+	return server
+}
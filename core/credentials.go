@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"r4scan/util"
+	"strings"
+)
+
+// Security describes how node channels are protected. A node with a
+// certificate serves TLS and, when CAFile is set, only accepts clients
+// whose certificate was issued by that CA. A controller verifies the node
+// against CAFile and presents its own certificate when one is set. Token
+// is sent and checked as a bearer token on every call, it is refused over a
+// plaintext channel unless Insecure is set.
+type Security struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	Token    string
+	Insecure bool
+}
+
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (s *Security) ServerOptions() ([]grpc.ServerOption, error) {
+
+	var options []grpc.ServerOption
+
+	if s == nil {
+		return options, nil
+	}
+
+	if s.CertFile != "" {
+
+		certificate, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %v", err)
+		}
+
+		config := &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		if s.CAFile != "" {
+			if config.ClientCAs, err = util.LoadCertPool(s.CAFile); err != nil {
+				return nil, fmt.Errorf("tls: %v", err)
+			}
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		options = append(options, grpc.Creds(credentials.NewTLS(config)))
+
+	} else if s.CAFile != "" {
+		return nil, fmt.Errorf("tls: client verification requires a node certificate")
+	} else if s.Token != "" && !s.Insecure {
+		return nil, fmt.Errorf("tls: a token requires a tls channel")
+	}
+
+	if s.Token != "" {
+		options = append(options,
+			grpc.UnaryInterceptor(tokenUnaryInterceptor(s.Token)),
+			grpc.StreamInterceptor(tokenStreamInterceptor(s.Token)),
+		)
+	}
+
+	return options, nil
+}
+
+func (s *Security) DialOptions() ([]grpc.DialOption, error) {
+
+	if s == nil || (s.CertFile == "" && s.CAFile == "" && s.Token == "") {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	var (
+		options []grpc.DialOption
+		secure  = s.CertFile != "" || s.CAFile != ""
+	)
+
+	if secure {
+
+		config := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if s.CAFile != "" {
+			pool, err := util.LoadCertPool(s.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("tls: %v", err)
+			}
+			config.RootCAs = pool
+		}
+
+		if s.CertFile != "" {
+			certificate, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("tls: %v", err)
+			}
+			config.Certificates = []tls.Certificate{certificate}
+		}
+
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+
+	} else if s.Insecure {
+		options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		return nil, fmt.Errorf("tls: a token requires a tls channel")
+	}
+
+	if s.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(&tokenCredentials{
+			token:  s.Token,
+			secure: secure,
+		}))
+	}
+
+	return options, nil
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+
+	return map[string]string{
+		"authorization": "Bearer " + t.token,
+	}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+
+	return t.secure
+}
+
+func tokenUnaryInterceptor(token string) grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := checkToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func tokenStreamInterceptor(token string) grpc.StreamServerInterceptor {
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := checkToken(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkToken(ctx context.Context, token string) error {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	for _, value := range md.Get("authorization") {
+		if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") &&
+			subtle.ConstantTimeCompare([]byte(value[7:]), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "invalid token")
+}
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"r4scan/core/local"
+	"r4scan/generator"
+	"r4scan/http"
+	"r4scan/scan"
+	"strings"
+	"sync"
+	"time"
+)
+
+func (s *Server) Create(request *local.CreateRequest, stream local.R4Scan_CreateServer) error {
+
+	//a node only scans the paths expanded by its controller, the dictionary
+	//options would make it read its local files
+	if s.node {
+		if len(request.Path) == 0 {
+			return status.Error(codes.InvalidArgument, "node: no path specified")
+		}
+		request.Dict = nil
+		request.DictPath = ""
+	}
+
+	client, dead, err := newClient(request)
+	if s.logger != nil {
+		for _, result := range dead {
+			s.logger(result)
+		}
+	}
+
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	defer client.Close()
+
+	urls, gen, err := newGenerator(stream.Context(), request)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	matcher := scan.NewMatcher().
+		SetStatus(request.Status).
+		SetIgnore(request.Ignore).
+		SetRequired(request.Required)
+
+	executor := scan.NewExecutor(client).
+		SetThread(int(request.Thread)).
+		SetMaxSpeed(int(request.MaxSpeed)).
+		SetDelay(time.Duration(request.Delay) * time.Millisecond)
+
+	var (
+		mu      sync.Mutex
+		sendErr error
+	)
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	//stopping the scan also aborts the pending proxy dials
+	client.SetContext(ctx)
+
+	executor.Run(ctx, urls, func(url string, response *fasthttp.Response, err error) {
+
+		if err != nil {
+			return
+		}
+
+		match := matcher.Match(response)
+		if !match.Hit {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if sendErr != nil {
+			return
+		}
+
+		if sendErr = stream.Send(&local.CreateReply{
+			Url:    url,
+			Status: int32(match.Status),
+			Length: int64(len(response.Body())),
+			Rule:   match.Rule,
+		}); sendErr != nil {
+			cancel()
+		}
+	})
+
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if err = gen.Err(); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return stream.Context().Err()
+}
+
+// newClient builds the client of request, dead holds the proxies that failed
+// the proxy check
+func newClient(request *local.CreateRequest) (client *http.Client, dead []*http.CheckResult, err error) {
+
+	client = http.NewClient().
+		SetCertificateVerify(true).
+		SetMethod(request.Method).
+		SetRetry(int(request.Retry)).
+		SetBody(request.Body).
+		SetHeader(request.Header)
+
+	if request.Timeout > 0 {
+		client.SetTimeout(time.Duration(request.Timeout) * time.Millisecond)
+	}
+
+	if request.UserAgent != "" && !strings.EqualFold(request.UserAgent, "random") {
+		client.SetUserAgent(request.UserAgent)
+	}
+
+	if request.XForwardedFor != "" {
+		client.SetXForwardedFor(request.XForwardedFor)
+	}
+
+	//the check and the pool share one chain, it is closed with the client
+	chain, err := NewProxyChain(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(request.Proxy) > 0 {
+		var pool *http.ProxyPool
+		if pool, dead, err = NewProxyPool(request, chain); err != nil {
+			if chain != nil {
+				_ = chain.Close()
+			}
+			return nil, dead, err
+		}
+		client.SetProxyPool(pool)
+	} else if chain != nil {
+		client.SetProxyChain(chain)
+	}
+
+	return client, dead, nil
+}
+
+// NewProxy parses rawUrl and applies the proxy options of request
+func NewProxy(request *local.CreateRequest, rawUrl string) (*http.Proxy, error) {
+
+	proxy, err := http.NewProxy(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ProxyMux > 0 {
+		proxy.SetMux(int(request.ProxyMux))
+	}
+
+	switch request.ProxyDns {
+	case "":
+	case "remote":
+		proxy.SetRemoteDNS()
+	default:
+		proxy.SetResolver(http.NewResolver(request.ProxyDns))
+	}
+
+	return proxy, nil
+}
+
+func NewProxyChain(request *local.CreateRequest) (*http.ProxyChain, error) {
+
+	if len(request.ProxyChain) == 0 {
+		return nil, nil
+	}
+
+	var hops []*http.Proxy
+
+	for _, rawUrl := range request.ProxyChain {
+		proxy, err := NewProxy(request, rawUrl)
+		if err != nil {
+			closeProxies(hops)
+			return nil, fmt.Errorf("invalid chain proxy \"%s\": %v", rawUrl, err)
+		}
+		hops = append(hops, proxy)
+	}
+
+	return http.NewProxyChain(hops...), nil
+}
+
+// NewProxyPool builds the pool described by request, checking the proxies
+// first when asked to. The proxies failing the check are returned as dead.
+// The pool reaches its proxies through chain, if any, and closes it along
+// with itself.
+func NewProxyPool(request *local.CreateRequest, chain *http.ProxyChain) (pool *http.ProxyPool, dead []*http.CheckResult, err error) {
+
+	var (
+		proxies []*http.Proxy
+		latency []time.Duration
+	)
+
+	for _, rawUrl := range request.Proxy {
+		proxy, err := NewProxy(request, rawUrl)
+		if err != nil {
+			closeProxies(proxies)
+			return nil, nil, fmt.Errorf("invalid proxy \"%s\": %v", rawUrl, err)
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	if request.ProxyCheck {
+
+		var alive []*http.CheckResult
+		if alive, dead, err = CheckProxies(request, proxies, chain); err != nil {
+			return nil, dead, err
+		}
+
+		proxies = proxies[:0]
+		for _, result := range alive {
+			proxies = append(proxies, result.Proxy)
+			latency = append(latency, result.Latency())
+		}
+	}
+
+	pool = http.NewProxyPool(proxies)
+
+	if chain != nil {
+		pool.SetChain(chain)
+	}
+
+	if request.ProxyStrategy != "" {
+		if _, err := pool.SetStrategy(request.ProxyStrategy); err != nil {
+			//the chain belongs to the caller until the pool is returned
+			closeProxies(proxies)
+			return nil, dead, err
+		}
+	}
+
+	//seed the latency strategy with the measured values
+	for i, duration := range latency {
+		pool.Proxies()[i].SetLatency(duration)
+	}
+
+	return pool, dead, nil
+}
+
+// CheckProxies runs the proxy check configured in request and returns the
+// passing proxies ordered by latency. The failing ones are closed and
+// returned as dead. The proxies are reached through chain when it is set.
+func CheckProxies(request *local.CreateRequest, proxies []*http.Proxy, chain *http.ProxyChain) (alive, dead []*http.CheckResult, err error) {
+
+	checker := http.NewProxyChecker().
+		SetThread(int(request.Thread)).
+		SetBody(request.ProxyCheckBody).
+		SetMaxLatency(time.Duration(request.ProxyMaxLatency) * time.Millisecond)
+
+	if request.ProxyCheckUrl != "" {
+		checker.SetCheckUrl(request.ProxyCheckUrl)
+	}
+
+	if request.ProxyCheckStatus > 0 {
+		checker.SetStatus(int(request.ProxyCheckStatus))
+	}
+
+	if request.Timeout > 0 {
+		checker.SetTimeout(time.Duration(request.Timeout) * time.Millisecond)
+	}
+
+	if chain != nil {
+		checker.SetChain(chain)
+	}
+
+	alive, dead = checker.Alive(proxies)
+
+	for _, result := range dead {
+		_ = result.Proxy.Close()
+	}
+
+	if len(alive) == 0 {
+		return nil, dead, fmt.Errorf("no proxy passed the check")
+	}
+
+	return alive, dead, nil
+}
+
+// closeProxies shuts down proxies built before an error, the v2ray based ones
+// keep a running core otherwise
+func closeProxies(proxies []*http.Proxy) {
+
+	for _, proxy := range proxies {
+		_ = proxy.Close()
+	}
+}
+
+func newGenerator(ctx context.Context, request *local.CreateRequest) (<-chan string, *generator.Generator, error) {
+
+	gen := generator.NewGenerator(request.DictPath).
+		SetTarget(request.Url).
+		SetDict(request.Dict).
+		SetComment(request.DictComment).
+		SetPath(request.Path).
+		SetExt(request.Ext).
+		SetVariable(request.Variable)
+
+	urls, err := gen.Generate(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return urls, gen, nil
+}
@@ -0,0 +1,331 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Generator struct {
+	target   []string
+	dict     []string
+	dictPath string
+	comment  string
+	path     []string
+	ext      []string
+	variable []dictVariable
+	mu       sync.Mutex
+	err      error
+}
+
+type dictVariable struct {
+	pattern *regexp.Regexp
+	value   string
+}
+
+var extPattern = regexp.MustCompile(`(?i)%EXT%`)
+
+func NewGenerator(dictPath string) *Generator {
+	return &Generator{
+		dictPath: dictPath,
+	}
+}
+
+func (g *Generator) SetTarget(target []string) *Generator {
+
+	g.target = target
+	return g
+}
+
+func (g *Generator) SetDict(dict []string) *Generator {
+
+	g.dict = dict
+	return g
+}
+
+// SetComment skips the dictionary lines starting with prefix, every line is
+// a path when no prefix is set
+func (g *Generator) SetComment(prefix string) *Generator {
+
+	g.comment = prefix
+	return g
+}
+
+// SetPath uses already expanded entries instead of reading dictionaries
+func (g *Generator) SetPath(path []string) *Generator {
+
+	g.path = path
+	return g
+}
+
+func (g *Generator) SetExt(ext []string) *Generator {
+
+	for _, v := range ext {
+		if v = strings.TrimLeft(strings.TrimSpace(v), "."); v != "" {
+			g.ext = append(g.ext, v)
+		}
+	}
+
+	return g
+}
+
+func (g *Generator) SetVariable(variable map[string]string) *Generator {
+
+	for key, value := range variable {
+		g.variable = append(g.variable, dictVariable{
+			pattern: regexp.MustCompile(`(?i)%` + regexp.QuoteMeta(key) + `%`),
+			value:   value,
+		})
+	}
+
+	return g
+}
+
+// Files resolves the dictionary names against dictPath. When no dictionary
+// is named, every regular file under dictPath is used.
+func (g *Generator) Files() (files []string, err error) {
+
+	if len(g.dict) == 0 {
+
+		entries, err := os.ReadDir(g.dictPath)
+		if err != nil {
+			return nil, fmt.Errorf("dict: %v", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, filepath.Join(g.dictPath, entry.Name()))
+			}
+		}
+
+		if len(files) == 0 {
+			return nil, fmt.Errorf("dict: no dictionary found in \"%s\"", g.dictPath)
+		}
+
+		sort.Strings(files)
+		return files, nil
+	}
+
+	for _, name := range g.dict {
+
+		file, err := g.resolve(name)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return
+}
+
+func (g *Generator) resolve(name string) (string, error) {
+
+	candidates := []string{
+		name,
+		filepath.Join(g.dictPath, name),
+		filepath.Join(g.dictPath, name+".txt"),
+	}
+
+	for _, file := range candidates {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file, nil
+		}
+	}
+
+	return "", fmt.Errorf("dict: dictionary \"%s\" not found", name)
+}
+
+// Generate streams candidate URLs for every target. Dictionaries are read
+// line by line for each target, so memory use does not grow with the size
+// of the wordlist. The channel is closed when generation finishes or ctx is
+// cancelled; Err reports the first read error.
+func (g *Generator) Generate(ctx context.Context) (<-chan string, error) {
+
+	if len(g.target) == 0 {
+		return nil, fmt.Errorf("dict: no target specified")
+	}
+
+	source, err := g.source()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1024)
+
+	go func() {
+
+		defer close(ch)
+
+		for _, target := range g.target {
+
+			base := strings.TrimRight(target, "/") + "/"
+
+			if err := source(ctx, func(path string) bool {
+				select {
+				case ch <- base + path:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}); err != nil {
+				g.setErr(err)
+				return
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Paths streams the expanded dictionary entries once, without targets
+func (g *Generator) Paths(ctx context.Context) (<-chan string, error) {
+
+	source, err := g.source()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1024)
+
+	go func() {
+
+		defer close(ch)
+
+		if err := source(ctx, func(path string) bool {
+			select {
+			case ch <- path:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}); err != nil {
+			g.setErr(err)
+		}
+	}()
+
+	return ch, nil
+}
+
+func (g *Generator) source() (func(ctx context.Context, yield func(path string) bool) error, error) {
+
+	if len(g.path) > 0 {
+		return func(ctx context.Context, yield func(path string) bool) error {
+			for _, path := range g.path {
+				if !yield(path) {
+					return nil
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	files, err := g.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, yield func(path string) bool) error {
+		for _, file := range files {
+			if err := g.readFile(ctx, file, yield); err != nil {
+				return err
+			}
+
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+		return nil
+	}, nil
+}
+
+// Err reports the first read error, it may be called while the generation
+// is still running
+func (g *Generator) Err() error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.err
+}
+
+func (g *Generator) setErr(err error) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+func (g *Generator) readFile(ctx context.Context, file string, yield func(path string) bool) error {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("dict: %v", err)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		//skip blank lines and comments, paths like #admin are kept by default
+		if line == "" || (g.comment != "" && strings.HasPrefix(line, g.comment)) {
+			continue
+		}
+
+		for _, path := range g.Expand(line) {
+			if !yield(path) {
+				return nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("dict: %s: %v", file, err)
+	}
+
+	return nil
+}
+
+// Expand substitutes the custom variables in a single dictionary entry and
+// returns one path per extension when the entry contains %EXT%. Entries
+// with %EXT% are dropped when no extension is set.
+func (g *Generator) Expand(line string) (paths []string) {
+
+	line = strings.TrimLeft(line, "/")
+
+	for _, v := range g.variable {
+		line = v.pattern.ReplaceAllLiteralString(line, v.value)
+	}
+
+	if !extPattern.MatchString(line) {
+		return []string{line}
+	}
+
+	for _, ext := range g.ext {
+		paths = append(paths, extPattern.ReplaceAllLiteralString(line, ext))
+	}
+
+	return
+}
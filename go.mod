@@ -3,15 +3,21 @@ module r4scan
 go 1.18
 
 require (
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358
+	github.com/Dreamacro/clash v1.11.8
 	github.com/alexflint/go-arg v1.4.3
 	github.com/go-playground/validator/v10 v10.11.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/sun8911879/shadowsocksR v0.0.0-20200921031217-b0d026c7a535
 	github.com/v2fly/v2ray-core/v4 v4.45.2
 	github.com/v2fly/vmessping v0.3.4
+	github.com/xtaci/smux v1.5.15
+	golang.org/x/crypto v0.0.0-20220826181053-bd7e27e6170d
+	gopkg.in/yaml.v3 v3.0.1
+	lukechampine.com/blake3 v1.1.7
 )
 
 require (
-	github.com/Dreamacro/clash v1.11.8 // indirect
 	github.com/aead/chacha20 v0.0.0-20180709150244-8b13a72661da // indirect
 	github.com/ajg/form v1.5.1 // indirect
 	github.com/cheekybits/genny v1.0.0 // indirect
@@ -28,9 +34,9 @@ require (
 	github.com/go-task/slim-sprig v0.0.0-20210107165309-348f09dbbbc0 // indirect
 	github.com/gofrs/uuid v4.2.0+incompatible // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/insomniacslk/dhcp v0.0.0-20220822114210-de18a9d48e84 // indirect
 	github.com/jhump/protoreflect v1.9.0 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.11 // indirect
 	github.com/lucas-clemente/quic-go v0.27.0 // indirect
 	github.com/lunixbochs/struc v0.0.0-20200707160740-784aaebc1d40 // indirect
 	github.com/marten-seemann/qtls-go1-16 v0.1.5 // indirect
@@ -48,7 +54,6 @@ require (
 	github.com/v2fly/BrowserBridge v0.0.0-20210430233438-0570fc1d7d08 // indirect
 	github.com/v2fly/VSign v0.0.0-20201108000810-e2adc24bf848 // indirect
 	github.com/v2fly/ss-bloomring v0.0.0-20210312155135-28617310f63e // indirect
-	github.com/xtaci/smux v1.5.15 // indirect
 	gitlab.com/yawning/chacha20.git v0.0.0-20190903091407-6d1cb28dc72c // indirect
 	go.etcd.io/bbolt v1.3.6 // indirect
 	go.starlark.net v0.0.0-20210901212718-87f333178d59 // indirect
@@ -60,7 +65,6 @@ require (
 	golang.org/x/sync v0.0.0-20220819030929-7fc1605a5dde // indirect
 	golang.org/x/tools v0.1.12 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	inet.af/netaddr v0.0.0-20210903134321-85fa6c94624e // indirect
 )
 
@@ -80,7 +84,6 @@ require (
 	github.com/shadowsocks/shadowsocks-go v0.0.0-20200409064450-3e585ff90601
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasthttp v1.39.0
-	golang.org/x/crypto v0.0.0-20220826181053-bd7e27e6170d // indirect
 	golang.org/x/net v0.0.0-20220826154423-83b083e8dc8b // indirect
 	golang.org/x/sys v0.0.0-20220825204002-c680a09ffe64 // indirect
 	golang.org/x/text v0.3.7 // indirect
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
-	"fmt"
 	"github.com/valyala/fasthttp"
+	"io"
+	"net"
 	"r4scan/util"
 	"strings"
 	"sync"
@@ -15,9 +17,10 @@ type Client struct {
 	method  string
 	header  sync.Map
 	body    string
-	proxy   *Proxy
+	proxy   io.Closer
 	retry   int
 	timeout time.Duration
+	ctx     context.Context
 }
 
 func NewClient() *Client {
@@ -25,6 +28,7 @@ func NewClient() *Client {
 		method:  "GET",
 		retry:   1,
 		timeout: time.Second * 5,
+		ctx:     context.Background(),
 		client: &fasthttp.Client{
 			DialDualStack:                 true,
 			MaxIdleConnDuration:           time.Hour,
@@ -51,6 +55,13 @@ func (c *Client) SetTimeout(time time.Duration) *Client {
 	return c
 }
 
+// SetContext aborts the pending proxy dials of the client once ctx is done
+func (c *Client) SetContext(ctx context.Context) *Client {
+
+	c.ctx = ctx
+	return c
+}
+
 func (c *Client) SetMethod(method string) *Client {
 
 	c.method = strings.ToUpper(method)
@@ -111,10 +122,45 @@ func (c *Client) SetBody(body string) *Client {
 
 func (c *Client) SetProxy(proxy *Proxy) *Client {
 
-	c.client.Dial = proxy.Dialer(c.timeout)
+	c.client.Dial = c.dialer(proxy.DialContext)
+	c.proxy = proxy
+	return c
+}
+
+func (c *Client) SetProxyChain(chain *ProxyChain) *Client {
+
+	c.client.Dial = c.dialer(chain.DialContext)
+	c.proxy = chain
+	return c
+}
+
+func (c *Client) SetProxyPool(pool *ProxyPool) *Client {
+
+	c.client.Dial = c.dialer(pool.DialContext)
+	c.proxy = pool
 	return c
 }
 
+// dialer bounds every dial by the timeout and the context of the client
+func (c *Client) dialer(dial DialContextFunc) fasthttp.DialFunc {
+
+	return func(addr string) (net.Conn, error) {
+		return FastHTTPDialer(c.ctx, c.timeout, dial)(addr)
+	}
+}
+
+// Close releases the idle connections and shuts down the proxies of the client
+func (c *Client) Close() error {
+
+	c.client.CloseIdleConnections()
+
+	if c.proxy != nil {
+		return c.proxy.Close()
+	}
+
+	return nil
+}
+
 func (c *Client) SetRetry(retry int) *Client {
 
 	c.retry = retry
@@ -150,7 +196,6 @@ func (c *Client) Do(url string) (response *fasthttp.Response, err error) {
 	response = fasthttp.AcquireResponse()
 
 	for i := 0; i <= c.retry; i++ {
-		fmt.Println(88888)
 		if err = c.client.DoTimeout(request, response, c.timeout); err == nil {
 			break
 		}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/valyala/fasthttp"
+	"net"
 	"net/url"
 	"r4scan/http/v2ray"
 	"strconv"
@@ -13,6 +15,7 @@ import (
 )
 
 type Proxy struct {
+	Name         string
 	Server       string `validate:"required,fqdn|ip4_addr"`
 	Port         int    `validate:"required,min=1,max=65535"`
 	Schema       string `validate:"required"`
@@ -20,11 +23,19 @@ type Proxy struct {
 	Pass         string
 	Auth         bool
 	Url          *url.URL
+	Raw          string
+	HTTPS        HTTPS
 	ShadowSocks  ShadowSocks
 	ShadowSocksR ShadowSocksR
 	VMess        v2ray.OutBounds
 	VLess        v2ray.OutBounds
 	Trojan       v2ray.OutBounds
+	TrojanGo     TrojanGo
+	core         v2rayInstance
+	mux          trojanGoMux
+	httpAuth     httpAuthCache
+	resolver     Resolver
+	remoteDNS    bool
 }
 
 var SchemaList = map[string]struct{}{
@@ -49,62 +60,208 @@ func (proxy *Proxy) String() string {
 
 func (proxy *Proxy) ConnectTest() (err error) {
 
-	//check 204
-	checkUrl := "http://www.gstatic.com/generate_204"
+	return NewProxyChecker().Check(proxy).Err
+}
 
-	//new http client
-	client := NewClient().
-		SetProxy(proxy).
-		SetRetry(1).
-		SetTimeout(time.Second * 5).
-		SetMethod("HEAD")
+// DialContextFunc opens a connection to addr and gives up as soon as ctx is
+// done, the proxies only support the "tcp" network
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
 
-	resp, err := client.Do(checkUrl)
+func (proxy *Proxy) Dialer(timeout time.Duration) fasthttp.DialFunc {
 
-	defer ReleaseResponse(resp)
+	return FastHTTPDialer(context.Background(), timeout, proxy.DialContext)
+}
 
-	if err != nil {
-		return err
+// DialContext connects to addr through the proxy, cancelling ctx aborts the
+// connection to the proxy server and the proxy negotiation
+func (proxy *Proxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	if err := checkNetwork(network); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode() != 204 {
-		return fmt.Errorf("invalid status code: %d", resp.StatusCode())
+	dial := proxy.dialer(directDialContext)
+	if dial == nil {
+		return nil, fmt.Errorf("unsupported proxy: %s", proxy.String())
 	}
 
-	return
+	return dial(ctx, network, addr)
 }
 
-func (proxy *Proxy) Dialer(timeout time.Duration) fasthttp.DialFunc {
+// dialer opens the connection to the proxy server with dial, the v2ray based
+// proxies always dial by themselves
+func (proxy *Proxy) dialer(dial DialContextFunc) DialContextFunc {
 
 	switch proxy.Schema {
 	case "HTTP", "HTTPS":
-		return proxy.HTTPDialer(timeout)
+		return proxy.httpDialer(dial)
 	case "SOCKS5", "SOCKS5H", "SOCKS4", "SOCKS4A":
-		return proxy.SocksDialer(timeout)
+		return proxy.socksDialer(dial)
 	case "SS":
-		return proxy.ShadowSocksDialer(timeout)
+		return proxy.shadowSocksDialer(dial)
 	case "SSR":
-		return proxy.ShadowSocksRDialer(timeout)
+		return proxy.shadowSocksRDialer(dial)
 	case "VMESS":
-		return proxy.VMessDialer(timeout)
+		return proxy.v2rayDialer(proxy.VMess)
 	case "VLESS":
-		return proxy.VLessDialer(timeout)
-	case "TROJAN", "TROJAN-GO":
-		return proxy.TrojanDialer(timeout)
+		return proxy.v2rayDialer(proxy.VLess)
+	case "TROJAN":
+		return proxy.v2rayDialer(proxy.Trojan)
+	case "TROJAN-GO":
+		return proxy.trojanGoDialer(dial)
 	default:
 		return nil
 	}
 }
 
+// dialsThrough reports whether the proxy connection is opened with the dial
+// function handed to dialer
+func (proxy *Proxy) dialsThrough() bool {
+
+	switch proxy.Schema {
+	case "HTTP", "HTTPS", "SOCKS5", "SOCKS5H", "SOCKS4", "SOCKS4A", "SS", "SSR", "TROJAN-GO":
+		return true
+	default:
+		return false
+	}
+}
+
+// DialerThrough opens the connection to the proxy server with dial instead of
+// a direct tcp connection. The v2ray based proxies dial by themselves and
+// cannot be tunneled.
+func (proxy *Proxy) DialerThrough(dial DialContextFunc) DialContextFunc {
+
+	if !proxy.dialsThrough() {
+		return errorDialer(fmt.Errorf("%s proxies cannot be tunneled", strings.ToLower(proxy.Schema)))
+	}
+
+	return proxy.dialer(dial)
+}
+
+// Tunnel negotiates the proxy inside conn, an open connection to the proxy
+// server, and returns the connection to addr
+func (proxy *Proxy) Tunnel(conn net.Conn, addr string) (net.Conn, error) {
+
+	used := false
+
+	return proxy.DialerThrough(func(context.Context, string, string) (net.Conn, error) {
+		if used {
+			return nil, fmt.Errorf("tunnel connection already used")
+		}
+		used = true
+		return conn, nil
+	})(context.Background(), "tcp", addr)
+}
+
+// FastHTTPDialer adapts dial to fasthttp. Every dial is bounded by timeout and
+// aborted once ctx is done, so stopping a scan tears down its pending dials.
+func FastHTTPDialer(ctx context.Context, timeout time.Duration, dial DialContextFunc) fasthttp.DialFunc {
+
+	return func(addr string) (net.Conn, error) {
+
+		dialCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return dial(dialCtx, "tcp", addr)
+	}
+}
+
+// handshake runs negotiate on conn, a new connection to the proxy server, and
+// closes conn when it fails. The deadline of ctx applies to conn meanwhile and
+// cancelling ctx aborts the read or write in progress.
+func handshake(ctx context.Context, conn net.Conn, negotiate func() error) error {
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var (
+		done    = make(chan struct{})
+		aborted = make(chan error, 1)
+	)
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				//unblock the negotiation
+				_ = conn.SetDeadline(time.Unix(1, 0))
+				aborted <- ctx.Err()
+			case <-done:
+				aborted <- nil
+			}
+		}()
+	} else {
+		aborted <- nil
+	}
+
+	err := negotiate()
+
+	close(done)
+	if ctxErr := <-aborted; ctxErr != nil {
+		err = ctxErr
+	}
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
+func checkNetwork(network string) error {
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return nil
+	default:
+		return fmt.Errorf("unsupported network: %s", network)
+	}
+}
+
+func errorDialer(err error) DialContextFunc {
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, err
+	}
+}
+
+func directDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	var dialer net.Dialer
+
+	return dialer.DialContext(ctx, network, addr)
+}
+
 func NewProxy(rawUrl string) (proxy *Proxy, err error) {
 
+	rawUrl = strings.TrimSpace(rawUrl)
+
+	if proxy, err = newProxy(rawUrl); err != nil {
+		return nil, err
+	}
+
+	proxy.Raw = rawUrl
+	if proxy.Name == "" && proxy.Url != nil {
+		proxy.Name = proxy.Url.Fragment
+	}
+
+	return
+}
+
+func newProxy(rawUrl string) (proxy *Proxy, err error) {
+
 	//validate
 	var validate = validator.New()
 
 	//parse url
 	urls, err := url.Parse(rawUrl)
 	if err != nil {
-		fmt.Println(err)
 		return
 	}
 
@@ -124,7 +281,7 @@ func NewProxy(rawUrl string) (proxy *Proxy, err error) {
 		return newVMess(urls, rawUrl)
 	case "VLESS":
 		return newVLess(urls)
-	case "TROJAN":
+	case "TROJAN", "TROJAN-GO":
 		return newTrojan(urls)
 	}
 
@@ -164,5 +321,11 @@ func NewProxy(rawUrl string) (proxy *Proxy, err error) {
 		Url:    urls,
 	}
 
+	if schema == "HTTPS" {
+		if proxy.HTTPS, err = newHTTPS(urls, server); err != nil {
+			return nil, err
+		}
+	}
+
 	return
 }
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"time"
+)
+
+// ProxyChain reaches the target through several proxies in order, every hop
+// is negotiated inside the connection of the previous one. Only the first
+// hop may be a v2ray based proxy.
+type ProxyChain struct {
+	proxies []*Proxy
+}
+
+func NewProxyChain(proxies ...*Proxy) *ProxyChain {
+	return &ProxyChain{
+		proxies: proxies,
+	}
+}
+
+func (chain *ProxyChain) Len() int {
+
+	return len(chain.proxies)
+}
+
+func (chain *ProxyChain) Proxies() []*Proxy {
+
+	return chain.proxies
+}
+
+func (chain *ProxyChain) String() string {
+
+	hops := make([]string, len(chain.proxies))
+	for i, proxy := range chain.proxies {
+		hops[i] = proxy.String()
+	}
+
+	return strings.Join(hops, " -> ")
+}
+
+func (chain *ProxyChain) Close() error {
+
+	var err error
+
+	for _, proxy := range chain.proxies {
+		if closeErr := proxy.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (chain *ProxyChain) Dialer(timeout time.Duration) fasthttp.DialFunc {
+
+	return FastHTTPDialer(context.Background(), timeout, chain.DialContext)
+}
+
+// DialContext connects to addr through every hop of the chain, cancelling ctx
+// aborts the negotiation with the hop in progress
+func (chain *ProxyChain) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	return chain.dialer(directDialContext)(ctx, network, addr)
+}
+
+func (chain *ProxyChain) dialer(dial DialContextFunc) DialContextFunc {
+
+	if len(chain.proxies) == 0 {
+		return errorDialer(fmt.Errorf("chain: no proxy"))
+	}
+
+	for i, proxy := range chain.proxies {
+
+		var next DialContextFunc
+
+		if i == 0 {
+			next = proxy.dialer(dial)
+		} else {
+			next = proxy.DialerThrough(dial)
+		}
+
+		if next == nil {
+			return errorDialer(fmt.Errorf("chain: unsupported proxy: %s", proxy.String()))
+		}
+
+		dial = next
+	}
+
+	return dial
+}
@@ -0,0 +1,296 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProxyChecker tests proxies against a check URL and measures every stage
+// of the request
+type ProxyChecker struct {
+	checkUrl   string
+	status     int
+	body       string
+	timeout    time.Duration
+	thread     int
+	maxLatency time.Duration
+	chain      *ProxyChain
+}
+
+// CheckResult holds the timings of one check. Connect is the TCP connect to
+// the proxy server, Handshake the proxy negotiation (plus the TLS handshake
+// with the check host for https) and FirstByte the wait for the first byte
+// of the response.
+type CheckResult struct {
+	Proxy     *Proxy
+	Connect   time.Duration
+	Handshake time.Duration
+	FirstByte time.Duration
+	Err       error
+}
+
+func (r *CheckResult) Latency() time.Duration {
+
+	return r.Connect + r.Handshake + r.FirstByte
+}
+
+func NewProxyChecker() *ProxyChecker {
+	return &ProxyChecker{
+		checkUrl: "http://www.gstatic.com/generate_204",
+		status:   204,
+		timeout:  time.Second * 5,
+		thread:   20,
+	}
+}
+
+func (c *ProxyChecker) SetCheckUrl(checkUrl string) *ProxyChecker {
+
+	c.checkUrl = checkUrl
+	return c
+}
+
+func (c *ProxyChecker) SetStatus(status int) *ProxyChecker {
+
+	c.status = status
+	return c
+}
+
+func (c *ProxyChecker) SetBody(body string) *ProxyChecker {
+
+	c.body = body
+	return c
+}
+
+func (c *ProxyChecker) SetTimeout(timeout time.Duration) *ProxyChecker {
+
+	c.timeout = timeout
+	return c
+}
+
+func (c *ProxyChecker) SetThread(thread int) *ProxyChecker {
+
+	if thread > 0 {
+		c.thread = thread
+	}
+	return c
+}
+
+func (c *ProxyChecker) SetMaxLatency(maxLatency time.Duration) *ProxyChecker {
+
+	c.maxLatency = maxLatency
+	return c
+}
+
+// SetChain checks the proxies as reached through the chain, Connect then
+// covers the whole chain
+func (c *ProxyChecker) SetChain(chain *ProxyChain) *ProxyChecker {
+
+	c.chain = chain
+	return c
+}
+
+// CheckAll tests the proxies concurrently, the results keep the input order
+func (c *ProxyChecker) CheckAll(proxies []*Proxy) []*CheckResult {
+
+	var (
+		results = make([]*CheckResult, len(proxies))
+		queue   = make(chan int)
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < c.thread && i < len(proxies); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				results[index] = c.Check(proxies[index])
+			}
+		}()
+	}
+
+	for i := range proxies {
+		queue <- i
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// Alive returns the passing results ordered by latency
+func (c *ProxyChecker) Alive(proxies []*Proxy) (alive []*CheckResult, dead []*CheckResult) {
+
+	for _, result := range c.CheckAll(proxies) {
+		if result.Err == nil {
+			alive = append(alive, result)
+		} else {
+			dead = append(dead, result)
+		}
+	}
+
+	sort.SliceStable(alive, func(i, j int) bool {
+		return alive[i].Latency() < alive[j].Latency()
+	})
+
+	return
+}
+
+func (c *ProxyChecker) Check(proxy *Proxy) (result *CheckResult) {
+
+	result = &CheckResult{Proxy: proxy}
+
+	checkUrl, err := url.Parse(c.checkUrl)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid check url: %v", err)
+		return
+	}
+
+	host := checkUrl.Hostname()
+	port := checkUrl.Port()
+	if port == "" {
+		if checkUrl.Scheme == "https" {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+
+	base := DialContextFunc(directDialContext)
+	if c.chain != nil {
+		base = c.chain.DialContext
+	}
+
+	//the deadline bounds the connection and the proxy handshake
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	//time the connection to the proxy server separately from the negotiation
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := base(ctx, network, addr)
+		result.Connect = time.Since(start)
+		return conn, err
+	}
+
+	var dialer DialContextFunc
+	if c.chain != nil {
+		dialer = proxy.DialerThrough(dial)
+	} else {
+		dialer = proxy.dialer(dial)
+	}
+
+	if dialer == nil {
+		result.Err = fmt.Errorf("unsupported proxy: %s", proxy.String())
+		return
+	}
+
+	//v2ray based proxies dial internally, probe the server instead
+	if !proxy.dialsThrough() {
+		conn, err := dial(ctx, "tcp", net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port)))
+		if err != nil {
+			result.Err = err
+			return
+		}
+		conn.Close()
+		start = time.Now()
+	}
+
+	conn, err := dialer(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	defer conn.Close()
+
+	if checkUrl.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err = tlsConn.SetDeadline(time.Now().Add(c.timeout)); err == nil {
+			err = tlsConn.Handshake()
+		}
+		if err != nil {
+			result.Err = err
+			return
+		}
+		conn = tlsConn
+	}
+
+	if proxy.dialsThrough() {
+		result.Handshake = time.Since(start) - result.Connect
+	} else {
+		result.Handshake = time.Since(start)
+	}
+
+	if err = conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		result.Err = err
+		return
+	}
+
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+
+	request.SetRequestURI(c.checkUrl)
+	request.Header.SetMethod(fasthttp.MethodGet)
+	request.Header.Set("Connection", "close")
+
+	firstByte := time.Now()
+
+	writer := bufio.NewWriter(conn)
+	if err = request.Write(writer); err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	if _, err = reader.Peek(1); err != nil {
+		result.Err = err
+		return
+	}
+
+	result.FirstByte = time.Since(firstByte)
+
+	if err = response.Read(reader); err != nil {
+		result.Err = err
+		return
+	}
+
+	if c.status > 0 && response.StatusCode() != c.status {
+		result.Err = fmt.Errorf("invalid status code: %d", response.StatusCode())
+		return
+	}
+
+	if c.body != "" {
+		body, err := response.BodyUncompressed()
+		if err != nil {
+			body = response.Body()
+		}
+		if !bytes.Contains(body, []byte(c.body)) {
+			result.Err = fmt.Errorf("check body not found")
+			return
+		}
+	}
+
+	if c.maxLatency > 0 && result.Latency() > c.maxLatency {
+		result.Err = fmt.Errorf("too slow: %s", result.Latency().Round(time.Millisecond))
+	}
+
+	return
+}
@@ -0,0 +1,510 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"os"
+	"r4scan/http/v2ray/protocol"
+	"r4scan/http/v2ray/stream"
+	"r4scan/validator"
+	"strconv"
+	"strings"
+
+	vdata "r4scan/http/v2ray"
+)
+
+// ClashProxy is one entry of the proxies section of a Clash profile
+type ClashProxy struct {
+	Name           string            `yaml:"name"`
+	Type           string            `yaml:"type"`
+	Server         string            `yaml:"server"`
+	Port           ClashPort         `yaml:"port"`
+	Username       string            `yaml:"username,omitempty"`
+	Password       string            `yaml:"password,omitempty"`
+	Cipher         string            `yaml:"cipher,omitempty"`
+	UUID           string            `yaml:"uuid,omitempty"`
+	AlterID        int               `yaml:"alterId,omitempty"`
+	TLS            bool              `yaml:"tls,omitempty"`
+	SkipCertVerify bool              `yaml:"skip-cert-verify,omitempty"`
+	ServerName     string            `yaml:"servername,omitempty"`
+	SNI            string            `yaml:"sni,omitempty"`
+	ALPN           []string          `yaml:"alpn,omitempty"`
+	Network        string            `yaml:"network,omitempty"`
+	Plugin         string            `yaml:"plugin,omitempty"`
+	PluginOpts     *ClashPluginOpts  `yaml:"plugin-opts,omitempty"`
+	Obfs           string            `yaml:"obfs,omitempty"`
+	ObfsParam      string            `yaml:"obfs-param,omitempty"`
+	Protocol       string            `yaml:"protocol,omitempty"`
+	ProtocolParam  string            `yaml:"protocol-param,omitempty"`
+	WSPath         string            `yaml:"ws-path,omitempty"`
+	WSHeaders      map[string]string `yaml:"ws-headers,omitempty"`
+	WSOpts         *ClashWSOpts      `yaml:"ws-opts,omitempty"`
+	HTTPOpts       *ClashHTTPOpts    `yaml:"http-opts,omitempty"`
+	H2Opts         *ClashH2Opts      `yaml:"h2-opts,omitempty"`
+	GrpcOpts       *ClashGrpcOpts    `yaml:"grpc-opts,omitempty"`
+}
+
+// ClashPort accepts both numbers and quoted strings, subscription converters
+// emit either
+type ClashPort int
+
+type ClashPluginOpts struct {
+	Mode           string            `yaml:"mode,omitempty"`
+	Host           string            `yaml:"host,omitempty"`
+	Path           string            `yaml:"path,omitempty"`
+	TLS            bool              `yaml:"tls,omitempty"`
+	Mux            *bool             `yaml:"mux,omitempty"`
+	SkipCertVerify bool              `yaml:"skip-cert-verify,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+}
+
+type ClashWSOpts struct {
+	Path                string            `yaml:"path,omitempty"`
+	Headers             map[string]string `yaml:"headers,omitempty"`
+	MaxEarlyData        int               `yaml:"max-early-data,omitempty"`
+	EarlyDataHeaderName string            `yaml:"early-data-header-name,omitempty"`
+}
+
+type ClashHTTPOpts struct {
+	Method  string              `yaml:"method,omitempty"`
+	Path    []string            `yaml:"path,omitempty"`
+	Headers map[string][]string `yaml:"headers,omitempty"`
+}
+
+type ClashH2Opts struct {
+	Host []string `yaml:"host,omitempty"`
+	Path string   `yaml:"path,omitempty"`
+}
+
+type ClashGrpcOpts struct {
+	ServiceName string `yaml:"grpc-service-name,omitempty"`
+}
+
+type clashConfig struct {
+	Proxies []yaml.Node `yaml:"proxies"`
+}
+
+func (p *ClashPort) UnmarshalYAML(node *yaml.Node) error {
+
+	port, err := strconv.Atoi(strings.TrimSpace(node.Value))
+	if err != nil || node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("clash: invalid port \"%s\"", node.Value)
+	}
+
+	*p = ClashPort(port)
+	return nil
+}
+
+func LoadClashFile(file string) ([]*Proxy, []error) {
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, []error{&ProxyLoadError{Source: file, Err: err}}
+	}
+
+	return ParseClash(file, data)
+}
+
+// ParseClash reads the proxies section of a Clash profile, errors carry the
+// line of the failing entry
+func ParseClash(source string, data []byte) (proxies []*Proxy, errs []error) {
+
+	var config clashConfig
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, []error{&ProxyLoadError{Source: source, Err: err}}
+	}
+
+	if len(config.Proxies) == 0 {
+		return nil, []error{&ProxyLoadError{Source: source, Err: fmt.Errorf("clash: no proxies found")}}
+	}
+
+	for i := range config.Proxies {
+
+		node := &config.Proxies[i]
+		entry := &ClashProxy{}
+
+		if err := node.Decode(entry); err != nil {
+			errs = append(errs, &ProxyLoadError{Source: source, Line: node.Line, Err: err})
+			continue
+		}
+
+		proxy, err := entry.Proxy()
+		if err != nil {
+			errs = append(errs, &ProxyLoadError{Source: source, Line: node.Line, Err: err})
+			continue
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	return
+}
+
+// Proxy converts the entry into a Proxy the existing dialers can use
+func (c *ClashProxy) Proxy() (proxy *Proxy, err error) {
+
+	server := strings.Trim(c.Server, "[]")
+
+	if err = validator.Var(server, "required,fqdn|ip"); err != nil {
+		return nil, fmt.Errorf("clash: invalid server \"%s\"", c.Server)
+	}
+
+	port := int(c.Port)
+	if err = validator.Var(port, "required,min=1,max=65535"); err != nil {
+		return nil, fmt.Errorf("clash: invalid port %d", port)
+	}
+
+	proxy = &Proxy{
+		Name:   c.Name,
+		Server: server,
+		Port:   port,
+		Url: &url.URL{
+			Scheme:   strings.ToLower(c.Type),
+			Host:     net.JoinHostPort(server, strconv.Itoa(port)),
+			Fragment: c.Name,
+		},
+	}
+
+	switch strings.ToLower(c.Type) {
+	case "ss":
+		err = c.shadowSocks(proxy)
+	case "ssr":
+		err = c.shadowSocksR(proxy)
+	case "vmess":
+		err = c.vMess(proxy)
+	case "vless":
+		err = c.vLess(proxy)
+	case "trojan":
+		err = c.trojan(proxy)
+	case "socks5":
+		if c.TLS {
+			return nil, fmt.Errorf("clash: socks5 over tls is not supported")
+		}
+		proxy.Schema = "SOCKS5"
+		c.basicAuth(proxy)
+	case "http":
+		proxy.Schema = "HTTP"
+		if c.TLS {
+			proxy.Schema = "HTTPS"
+			proxy.Url.Scheme = "https"
+			proxy.HTTPS = HTTPS{
+				SNI:           c.serverName(),
+				AllowInsecure: c.SkipCertVerify,
+			}
+		}
+		c.basicAuth(proxy)
+	default:
+		return nil, fmt.Errorf("clash: unsupported proxy type \"%s\"", c.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	//the share link lets the proxy travel as a plain string
+	if proxy.Raw, err = proxy.ShareLink(); err != nil {
+		return nil, err
+	}
+
+	return proxy, nil
+}
+
+func (c *ClashProxy) basicAuth(proxy *Proxy) {
+
+	if c.Username == "" && c.Password == "" {
+		return
+	}
+
+	proxy.User = c.Username
+	proxy.Pass = c.Password
+	proxy.Auth = true
+	proxy.Url.User = url.UserPassword(c.Username, c.Password)
+}
+
+func (c *ClashProxy) shadowSocks(proxy *Proxy) error {
+
+	cipher := strings.ToLower(c.Cipher)
+	if _, exist := ShadowSocksCipherList[cipher]; !exist {
+		return fmt.Errorf("clash: invalid ss cipher \"%s\"", c.Cipher)
+	}
+
+	proxy.Schema = "SS"
+	proxy.ShadowSocks = ShadowSocks{
+		Server:   proxy.Server,
+		Port:     proxy.Port,
+		Cipher:   cipher,
+		Password: c.Password,
+	}
+
+	if _, exist := ShadowSocks2022KeySize[cipher]; exist {
+		keys, err := newShadowSocks2022Keys(cipher, c.Password)
+		if err != nil {
+			return fmt.Errorf("clash: %v", err)
+		}
+		proxy.ShadowSocks.Keys = keys
+	}
+
+	switch strings.ToLower(c.Plugin) {
+	case "":
+	case "obfs", "obfs-local", "simple-obfs":
+		if c.PluginOpts == nil {
+			return fmt.Errorf("clash: missing ss obfs options")
+		}
+		mode := strings.ToLower(c.PluginOpts.Mode)
+		if mode != "http" && mode != "tls" {
+			return fmt.Errorf("clash: unknown obfs mode \"%s\"", c.PluginOpts.Mode)
+		}
+		proxy.ShadowSocks.Obfs = &ShadowSocksObfs{
+			Schema: mode,
+			Host:   c.PluginOpts.Host,
+		}
+	case "v2ray-plugin":
+		if c.PluginOpts == nil {
+			return fmt.Errorf("clash: missing ss v2ray-plugin options")
+		}
+		if mode := strings.ToLower(c.PluginOpts.Mode); mode != "websocket" {
+			return fmt.Errorf("clash: unsupported v2ray-plugin mode \"%s\"", c.PluginOpts.Mode)
+		}
+		plugin := &ShadowSocksV2rayPlugin{
+			Host:           c.PluginOpts.Host,
+			Path:           c.PluginOpts.Path,
+			Headers:        c.PluginOpts.Headers,
+			TLS:            c.PluginOpts.TLS,
+			SkipCertVerify: c.PluginOpts.SkipCertVerify,
+			//clash multiplexes unless told otherwise
+			Mux: c.PluginOpts.Mux == nil || *c.PluginOpts.Mux,
+		}
+		if plugin.Host == "" {
+			plugin.Host = proxy.Server
+		}
+		if plugin.Path == "" {
+			plugin.Path = "/"
+		}
+		proxy.ShadowSocks.V2rayPlugin = plugin
+	default:
+		return fmt.Errorf("clash: unknown ss plugin \"%s\"", c.Plugin)
+	}
+
+	return nil
+}
+
+func (c *ClashProxy) shadowSocksR(proxy *Proxy) error {
+
+	cipher := strings.ToLower(c.Cipher)
+	if _, exist := ShadowSocksRCipherList[cipher]; !exist {
+		return fmt.Errorf("clash: invalid ssr cipher \"%s\"", c.Cipher)
+	}
+
+	obfs := shadowSocksRName(c.Obfs)
+	if _, exist := ShadowSocksRObfsList[obfs]; !exist {
+		return fmt.Errorf("clash: invalid ssr obfs \"%s\"", c.Obfs)
+	}
+
+	protocol := shadowSocksRName(c.Protocol)
+	if _, exist := ShadowSocksRProtocolList[protocol]; !exist {
+		return fmt.Errorf("clash: invalid ssr protocol \"%s\"", c.Protocol)
+	}
+
+	proxy.Schema = "SSR"
+	proxy.ShadowSocksR = ShadowSocksR{
+		Server:   proxy.Server,
+		Port:     proxy.Port,
+		Cipher:   cipher,
+		Password: c.Password,
+		Obfs: &ShadowSocksRObfs{
+			Schema: obfs,
+			Param:  c.ObfsParam,
+		},
+		Protocol: &ShadowSocksRProtocol{
+			Schema: protocol,
+			Param:  c.ProtocolParam,
+		},
+	}
+
+	return nil
+}
+
+func (c *ClashProxy) vMess(proxy *Proxy) (err error) {
+
+	cipher := strings.ToLower(c.Cipher)
+	if cipher == "" {
+		cipher = "auto"
+	}
+
+	vmessSettings := &protocol.VMessSettings{
+		VMessVNext: []protocol.VMessVNext{
+			{
+				Address: proxy.Server,
+				Port:    proxy.Port,
+				Users: []protocol.VMessUsers{
+					{
+						ID:       c.UUID,
+						AlterId:  c.AlterID,
+						Security: cipher,
+					},
+				},
+			},
+		},
+	}
+
+	if err = validator.Validator(vmessSettings); err != nil {
+		return err
+	}
+
+	proxy.Schema = "VMESS"
+	proxy.VMess, err = c.outBounds("vmess", vmessSettings, c.TLS)
+	return
+}
+
+func (c *ClashProxy) vLess(proxy *Proxy) (err error) {
+
+	vlessSettings := &protocol.VLessSettings{
+		VLessVNext: []protocol.VLessVNext{
+			{
+				Address: proxy.Server,
+				Port:    proxy.Port,
+				Users: []protocol.VLessUsers{
+					{
+						ID:         c.UUID,
+						Encryption: "none",
+					},
+				},
+			},
+		},
+	}
+
+	if err = validator.Validator(vlessSettings); err != nil {
+		return err
+	}
+
+	proxy.Schema = "VLESS"
+	proxy.VLess, err = c.outBounds("vless", vlessSettings, c.TLS)
+	return
+}
+
+func (c *ClashProxy) trojan(proxy *Proxy) (err error) {
+
+	trojanSettings := &protocol.TrojanSettings{
+		TrojanServers: []protocol.TrojanServers{
+			{
+				Address:  proxy.Server,
+				Port:     proxy.Port,
+				Password: c.Password,
+			},
+		},
+	}
+
+	if err = validator.Validator(trojanSettings); err != nil {
+		return err
+	}
+
+	proxy.Schema = "TROJAN"
+	proxy.Trojan, err = c.outBounds("trojan", trojanSettings, true)
+	return
+}
+
+func (c *ClashProxy) outBounds(protocol string, settings interface{}, tls bool) (outBounds vdata.OutBounds, err error) {
+
+	outBounds.Protocol = protocol
+
+	if outBounds.StreamSettings, err = c.streamSettings(tls); err != nil {
+		return
+	}
+
+	if outBounds.Settings, err = json.Marshal(settings); err != nil {
+		return
+	}
+
+	err = validator.Validator(outBounds)
+	return
+}
+
+func (c *ClashProxy) streamSettings(tls bool) (*vdata.StreamSettings, error) {
+
+	streamSetting := &vdata.StreamSettings{}
+
+	switch strings.ToLower(c.Network) {
+	case "", "tcp":
+		streamSetting.Network = "tcp"
+		streamSetting.TCPSettings = &stream.TCPSettings{}
+	case "ws":
+		streamSetting.Network = "ws"
+		streamSetting.WSSettings = &stream.WSSettings{
+			Path:    c.WSPath,
+			Headers: c.WSHeaders,
+		}
+		//ws-path and ws-headers are the old spelling of ws-opts
+		if c.WSOpts != nil {
+			if c.WSOpts.Path != "" {
+				streamSetting.WSSettings.Path = c.WSOpts.Path
+			}
+			if len(c.WSOpts.Headers) > 0 {
+				streamSetting.WSSettings.Headers = c.WSOpts.Headers
+			}
+			streamSetting.WSSettings.MaxEarlyData = c.WSOpts.MaxEarlyData
+			streamSetting.WSSettings.EarlyDataHeaderName = c.WSOpts.EarlyDataHeaderName
+		}
+	case "h2":
+		streamSetting.Network = "http"
+		streamSetting.HTTPSettings = &stream.HTTPSettings{}
+		if c.H2Opts != nil {
+			streamSetting.HTTPSettings.Host = c.H2Opts.Host
+			streamSetting.HTTPSettings.Path = c.H2Opts.Path
+		}
+		if len(streamSetting.HTTPSettings.Host) == 0 {
+			streamSetting.HTTPSettings.Host = []string{c.serverName()}
+		}
+	case "http":
+		streamSetting.Network = "tcp"
+		streamSetting.TCPSettings = &stream.TCPSettings{
+			TCPHeader: &stream.TCPHeader{
+				Type:    "http",
+				Request: &stream.TCPHeaderRequest{},
+			},
+		}
+		if c.HTTPOpts != nil {
+			streamSetting.TCPSettings.TCPHeader.Request.Method = c.HTTPOpts.Method
+			streamSetting.TCPSettings.TCPHeader.Request.Path = c.HTTPOpts.Path
+			streamSetting.TCPSettings.TCPHeader.Request.Headers = c.HTTPOpts.Headers
+		}
+	case "grpc":
+		streamSetting.Network = "grpc"
+		streamSetting.GRPCSettings = &stream.GRPCSettings{}
+		if c.GrpcOpts != nil {
+			streamSetting.GRPCSettings.ServiceName = c.GrpcOpts.ServiceName
+		}
+	default:
+		return nil, fmt.Errorf("clash: unsupported network \"%s\"", c.Network)
+	}
+
+	if tls {
+		streamSetting.Security = "tls"
+		streamSetting.TLSSettings = &stream.TLSSettings{
+			AllowInsecure: c.SkipCertVerify,
+			Alpn:          c.ALPN,
+		}
+		//v2ray falls back to the server address, which may be an ip
+		if serverName := c.serverName(); validator.Var(serverName, "fqdn") == nil {
+			streamSetting.TLSSettings.ServerName = serverName
+		}
+	}
+
+	return streamSetting, nil
+}
+
+func (c *ClashProxy) serverName() string {
+
+	if c.SNI != "" {
+		return c.SNI
+	}
+
+	if c.ServerName != "" {
+		return c.ServerName
+	}
+
+	return strings.Trim(c.Server, "[]")
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"testing"
+)
+
+const testClashProfile = `
+proxies:
+  - {name: http, type: http, server: example.com, port: 443, username: user, password: pass, tls: true, sni: proxy.example.com, skip-cert-verify: true}
+  - {name: socks5, type: socks5, server: example.com, port: 1080, username: user, password: pass}
+  - {name: ss, type: ss, server: example.com, port: 8388, cipher: aes-128-gcm, password: pass}
+  - {name: ss-obfs, type: ss, server: example.com, port: 8388, cipher: aes-128-gcm, password: pass, plugin: obfs, plugin-opts: {mode: http, host: bing.com}}
+  - name: ss-v2ray-plugin
+    type: ss
+    server: example.com
+    port: 8388
+    cipher: chacha20-ietf-poly1305
+    password: pass
+    plugin: v2ray-plugin
+    plugin-opts: {mode: websocket, host: cdn.example.com, path: /ws, tls: true, mux: false, skip-cert-verify: true, headers: {User-Agent: r4scan}}
+  - {name: ssr, type: ssr, server: example.com, port: 8388, cipher: aes-128-cfb, password: pass, obfs: http_simple, obfs-param: bing.com, protocol: auth_aes128_md5, protocol-param: "1:pass"}
+  - name: vmess-ws
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: cdn.example.com
+    skip-cert-verify: true
+    alpn: [h2, http/1.1]
+    network: ws
+    ws-opts: {path: /ws, headers: {Host: cdn.example.com, User-Agent: r4scan}, max-early-data: 2048, early-data-header-name: Sec-WebSocket-Protocol}
+  - name: vmess-http
+    type: vmess
+    server: example.com
+    port: 80
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    network: http
+    http-opts: {method: GET, path: [/a, /b], headers: {Host: [a.example.com, b.example.com], Connection: [keep-alive]}}
+  - name: vmess-h2
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    tls: true
+    network: h2
+    h2-opts: {host: [cdn.example.com], path: /h2}
+  - name: vless-grpc
+    type: vless
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    tls: true
+    alpn: [h2]
+    network: grpc
+    grpc-opts: {grpc-service-name: scan}
+  - name: vless-ws
+    type: vless
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    tls: true
+    network: ws
+    ws-opts: {path: /ws, headers: {Host: cdn.example.com, User-Agent: r4scan}, max-early-data: 2048}
+  - {name: trojan, type: trojan, server: example.com, port: 443, password: pass, sni: cdn.example.com, alpn: [h2, http/1.1], skip-cert-verify: true}
+  - name: trojan-ws
+    type: trojan
+    server: example.com
+    port: 443
+    password: pass
+    network: ws
+    ws-opts: {path: /ws, headers: {Host: cdn.example.com, User-Agent: r4scan}, early-data-header-name: Sec-WebSocket-Protocol, max-early-data: 2048}
+`
+
+// TestClashRoundTrip checks that the share link of every clash entry parses
+// back into the same proxy
+func TestClashRoundTrip(t *testing.T) {
+
+	proxies, errs := ParseClash("profile", []byte(testClashProfile))
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	for _, proxy := range proxies {
+		t.Run(proxy.Name, func(t *testing.T) {
+			parsed, err := NewProxy(proxy.Raw)
+			if err != nil {
+				t.Fatalf("%s: %v", proxy.Raw, err)
+			}
+			assertSameProxy(t, proxy, parsed)
+		})
+	}
+}
@@ -0,0 +1,834 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"r4scan/http/v2ray/protocol"
+	"r4scan/http/v2ray/stream"
+	"r4scan/util"
+	"sort"
+	"strconv"
+	"strings"
+
+	vdata "r4scan/http/v2ray"
+)
+
+var ExportFormatList = map[string]struct{}{
+	"link":   {},
+	"base64": {},
+	"clash":  {},
+	"v2ray":  {},
+}
+
+// v2ray only implements part of the shadowsocks ciphers, some under another name
+var v2rayShadowSocksCipherList = map[string]string{
+	"aes-128-cfb":            "aes-128-cfb",
+	"aes-256-cfb":            "aes-256-cfb",
+	"chacha20":               "chacha20",
+	"chacha20-ietf":          "chacha20-ietf",
+	"aes-128-gcm":            "aes-128-gcm",
+	"aes-256-gcm":            "aes-256-gcm",
+	"aead_aes_128_gcm":       "aes-128-gcm",
+	"aead_aes_256_gcm":       "aes-256-gcm",
+	"chacha20-ietf-poly1305": "chacha20-poly1305",
+	"aead_chacha20_poly1305": "chacha20-poly1305",
+}
+
+// ExportProxies serializes the proxies in one of ExportFormatList. Proxies the
+// format cannot express are reported and left out.
+func ExportProxies(proxies []*Proxy, format string) (data []byte, errs []error) {
+
+	switch format {
+	case "link", "base64":
+
+		var links []string
+		for _, proxy := range proxies {
+			link, err := proxy.ShareLink()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", proxy.String(), err))
+				continue
+			}
+			links = append(links, link)
+		}
+
+		data = []byte(strings.Join(links, "\n") + "\n")
+		if format == "base64" {
+			data = []byte(base64.StdEncoding.EncodeToString(data))
+		}
+
+	case "clash":
+
+		var (
+			entries []*ClashProxy
+			names   = map[string]int{}
+		)
+
+		for _, proxy := range proxies {
+			entry, err := proxy.Clash()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", proxy.String(), err))
+				continue
+			}
+			//clash refuses duplicate names
+			if count := names[entry.Name]; count > 0 {
+				names[entry.Name]++
+				entry.Name = fmt.Sprintf("%s %d", entry.Name, count+1)
+			}
+			names[entry.Name]++
+			entries = append(entries, entry)
+		}
+
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(map[string][]*ClashProxy{"proxies": entries}); err != nil {
+			return nil, append(errs, err)
+		}
+		data = buffer.Bytes()
+
+	case "v2ray":
+
+		var outBounds []vdata.OutBounds
+		for _, proxy := range proxies {
+			outBound, err := proxy.V2Ray()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", proxy.String(), err))
+				continue
+			}
+			outBounds = append(outBounds, outBound)
+		}
+
+		var err error
+		if data, err = json.MarshalIndent(map[string][]vdata.OutBounds{"outbounds": outBounds}, "", "  "); err != nil {
+			return nil, append(errs, err)
+		}
+
+	default:
+		return nil, []error{fmt.Errorf("export: invalid format \"%s\"", format)}
+	}
+
+	return
+}
+
+// ShareLink returns the canonical share link of the proxy, NewProxy parses it
+// back into the same proxy. Settings outside the common link formats travel
+// as extra parameters, like mux.
+func (proxy *Proxy) ShareLink() (string, error) {
+
+	switch proxy.Schema {
+	case "HTTP", "HTTPS", "SOCKS5", "SOCKS5H", "SOCKS4", "SOCKS4A":
+		link := &url.URL{
+			Scheme:   strings.ToLower(proxy.Schema),
+			Host:     net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port)),
+			Fragment: proxy.Name,
+		}
+		if proxy.Auth {
+			if proxy.Pass != "" {
+				link.User = url.UserPassword(proxy.User, proxy.Pass)
+			} else {
+				link.User = url.User(proxy.User)
+			}
+		}
+		if proxy.Schema == "HTTPS" {
+			link.RawQuery = proxy.HTTPS.query(proxy.Server).Encode()
+		}
+		return link.String(), nil
+	case "SS":
+		return proxy.shadowSocksLink(), nil
+	case "SSR":
+		return proxy.shadowSocksRLink(), nil
+	case "VMESS":
+		return proxy.vMessLink()
+	case "VLESS":
+		return proxy.vLessLink()
+	case "TROJAN", "TROJAN-GO":
+		return proxy.trojanLink()
+	default:
+		return "", fmt.Errorf("export: unsupported schema \"%s\"", proxy.Schema)
+	}
+}
+
+// Clash returns the proxy as an entry of a Clash profile
+func (proxy *Proxy) Clash() (entry *ClashProxy, err error) {
+
+	entry = &ClashProxy{
+		Name:   proxy.Name,
+		Server: proxy.Server,
+		Port:   ClashPort(proxy.Port),
+	}
+
+	if entry.Name == "" {
+		entry.Name = net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port))
+	}
+
+	switch proxy.Schema {
+	case "HTTP", "HTTPS":
+		entry.Type = "http"
+		entry.TLS = proxy.Schema == "HTTPS"
+		entry.Username = proxy.User
+		entry.Password = proxy.Pass
+		if entry.TLS {
+			if proxy.HTTPS.CA != "" {
+				return nil, fmt.Errorf("export: clash does not support a custom ca")
+			}
+			if proxy.HTTPS.SNI != proxy.Server {
+				entry.SNI = proxy.HTTPS.SNI
+			}
+			entry.SkipCertVerify = proxy.HTTPS.AllowInsecure
+		}
+	case "SOCKS5", "SOCKS5H":
+		entry.Type = "socks5"
+		entry.Username = proxy.User
+		entry.Password = proxy.Pass
+	case "SS":
+		entry.Type = "ss"
+		entry.Server = proxy.ShadowSocks.Server
+		entry.Port = ClashPort(proxy.ShadowSocks.Port)
+		entry.Cipher = proxy.ShadowSocks.Cipher
+		entry.Password = proxy.ShadowSocks.Password
+		if obfs := proxy.ShadowSocks.Obfs; obfs != nil {
+			entry.Plugin = "obfs"
+			entry.PluginOpts = &ClashPluginOpts{
+				Mode: obfs.Schema,
+				Host: obfs.Host,
+			}
+		}
+		if plugin := proxy.ShadowSocks.V2rayPlugin; plugin != nil {
+			entry.Plugin = "v2ray-plugin"
+			entry.PluginOpts = &ClashPluginOpts{
+				Mode:           "websocket",
+				Host:           plugin.Host,
+				Path:           plugin.Path,
+				TLS:            plugin.TLS,
+				Mux:            &plugin.Mux,
+				SkipCertVerify: plugin.SkipCertVerify,
+				Headers:        plugin.Headers,
+			}
+		}
+	case "SSR":
+		entry.Type = "ssr"
+		entry.Server = proxy.ShadowSocksR.Server
+		entry.Port = ClashPort(proxy.ShadowSocksR.Port)
+		entry.Cipher = proxy.ShadowSocksR.Cipher
+		entry.Password = proxy.ShadowSocksR.Password
+		if obfs := proxy.ShadowSocksR.Obfs; obfs != nil {
+			entry.Obfs = obfs.Schema
+			entry.ObfsParam = obfs.Param
+		}
+		if protocol := proxy.ShadowSocksR.Protocol; protocol != nil {
+			entry.Protocol = protocol.Schema
+			entry.ProtocolParam = protocol.Param
+		}
+	case "VMESS":
+		settings := &protocol.VMessSettings{}
+		if err = json.Unmarshal(proxy.VMess.Settings, settings); err != nil || len(settings.VMessVNext) == 0 || len(settings.VMessVNext[0].Users) == 0 {
+			return nil, fmt.Errorf("export: invalid vmess settings")
+		}
+		entry.Type = "vmess"
+		entry.UUID = settings.VMessVNext[0].Users[0].ID
+		entry.AlterID = settings.VMessVNext[0].Users[0].AlterId
+		entry.Cipher = settings.VMessVNext[0].Users[0].Security
+		err = entry.setStream(proxy.VMess.StreamSettings)
+	case "VLESS":
+		settings := &protocol.VLessSettings{}
+		if err = json.Unmarshal(proxy.VLess.Settings, settings); err != nil || len(settings.VLessVNext) == 0 || len(settings.VLessVNext[0].Users) == 0 {
+			return nil, fmt.Errorf("export: invalid vless settings")
+		}
+		entry.Type = "vless"
+		entry.UUID = settings.VLessVNext[0].Users[0].ID
+		err = entry.setStream(proxy.VLess.StreamSettings)
+	case "TROJAN", "TROJAN-GO":
+		//the trojan of clash is trojan-go without its mux
+		if proxy.Schema == "TROJAN-GO" && proxy.TrojanGo.Mux {
+			return nil, fmt.Errorf("export: clash does not support the trojan-go mux")
+		}
+		settings := &protocol.TrojanSettings{}
+		if err = json.Unmarshal(proxy.Trojan.Settings, settings); err != nil || len(settings.TrojanServers) == 0 {
+			return nil, fmt.Errorf("export: invalid trojan settings")
+		}
+		entry.Type = "trojan"
+		entry.Password = settings.TrojanServers[0].Password
+		if err = entry.setStream(proxy.Trojan.StreamSettings); err == nil {
+			//trojan is always tls and names the server sni
+			entry.TLS = false
+			entry.SNI, entry.ServerName = entry.ServerName, ""
+		}
+	default:
+		return nil, fmt.Errorf("export: schema \"%s\" is not supported by clash", proxy.Schema)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// V2Ray returns the proxy as a v2ray outbound tagged with the proxy name
+func (proxy *Proxy) V2Ray() (outBounds vdata.OutBounds, err error) {
+
+	switch proxy.Schema {
+	case "VMESS":
+		outBounds = proxy.VMess
+	case "VLESS":
+		outBounds = proxy.VLess
+	case "TROJAN", "TROJAN-GO":
+		if proxy.Schema == "TROJAN-GO" && proxy.TrojanGo.Mux {
+			return outBounds, fmt.Errorf("export: v2ray does not support the trojan-go mux")
+		}
+		outBounds = proxy.Trojan
+	case "SS":
+		if proxy.ShadowSocks.Obfs != nil {
+			return outBounds, fmt.Errorf("export: v2ray does not support simple-obfs")
+		}
+		if plugin := proxy.ShadowSocks.V2rayPlugin; plugin != nil && plugin.Mux {
+			return outBounds, fmt.Errorf("export: v2ray does not support the v2ray-plugin mux")
+		}
+		method, exist := v2rayShadowSocksCipherList[proxy.ShadowSocks.Cipher]
+		if !exist {
+			return outBounds, fmt.Errorf("export: cipher \"%s\" is not supported by v2ray", proxy.ShadowSocks.Cipher)
+		}
+		if plugin := proxy.ShadowSocks.V2rayPlugin; plugin != nil {
+			//without mux the plugin is shadowsocks over websocket
+			outBounds.StreamSettings = &vdata.StreamSettings{
+				Network: "ws",
+				WSSettings: &stream.WSSettings{
+					Path:    plugin.Path,
+					Headers: map[string]string{"Host": plugin.Host},
+				},
+			}
+			for name, value := range plugin.Headers {
+				outBounds.StreamSettings.WSSettings.Headers[name] = value
+			}
+			if plugin.TLS {
+				outBounds.StreamSettings.Security = "tls"
+				outBounds.StreamSettings.TLSSettings = &stream.TLSSettings{
+					ServerName:    plugin.Host,
+					AllowInsecure: plugin.SkipCertVerify,
+				}
+			}
+		}
+		outBounds.Protocol = "shadowsocks"
+		outBounds.Settings, err = json.Marshal(&protocol.ShadowSocksSettings{
+			ShadowSocksServers: []protocol.ShadowSocksServers{
+				{
+					Address:  proxy.ShadowSocks.Server,
+					Port:     proxy.ShadowSocks.Port,
+					Method:   method,
+					Password: proxy.ShadowSocks.Password,
+				},
+			},
+		})
+	case "SOCKS5", "SOCKS5H", "HTTP", "HTTPS":
+		server := protocol.SocksServers{
+			Address: proxy.Server,
+			Port:    proxy.Port,
+		}
+		if proxy.Auth {
+			server.Users = []protocol.SocksUsers{{User: proxy.User, Pass: proxy.Pass}}
+		}
+		outBounds.Protocol = "socks"
+		if strings.HasPrefix(proxy.Schema, "HTTP") {
+			outBounds.Protocol = "http"
+		}
+		if proxy.Schema == "HTTPS" {
+			outBounds.StreamSettings = &vdata.StreamSettings{
+				Network:  "tcp",
+				Security: "tls",
+				TLSSettings: &stream.TLSSettings{
+					AllowInsecure: proxy.HTTPS.AllowInsecure,
+				},
+			}
+			if net.ParseIP(proxy.HTTPS.SNI) == nil {
+				outBounds.StreamSettings.TLSSettings.ServerName = proxy.HTTPS.SNI
+			}
+			if proxy.HTTPS.CA != "" {
+				outBounds.StreamSettings.TLSSettings.Certificates = []stream.TLSCertificate{
+					{Usage: "verify", CertificateFile: proxy.HTTPS.CA},
+				}
+			}
+		}
+		outBounds.Settings, err = json.Marshal(&protocol.SocksSettings{
+			SocksServers: []protocol.SocksServers{server},
+		})
+	default:
+		return outBounds, fmt.Errorf("export: schema \"%s\" is not supported by v2ray", proxy.Schema)
+	}
+
+	outBounds.Tag = proxy.Name
+	return
+}
+
+func (proxy *Proxy) shadowSocksLink() string {
+
+	link := &url.URL{
+		Scheme:   "ss",
+		User:     url.User(util.Base64URLEncode(proxy.ShadowSocks.Cipher + ":" + proxy.ShadowSocks.Password)),
+		Host:     net.JoinHostPort(proxy.ShadowSocks.Server, strconv.Itoa(proxy.ShadowSocks.Port)),
+		Fragment: proxy.Name,
+	}
+
+	//sip002 wants the 2022 methods percent encoded
+	if len(proxy.ShadowSocks.Keys) > 0 {
+		link.User = url.UserPassword(proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Password)
+	}
+
+	if obfs := proxy.ShadowSocks.Obfs; obfs != nil {
+		link.Path = "/"
+		link.RawQuery = "plugin=" + url.QueryEscape("obfs-local;obfs="+obfs.Schema+";obfs-host="+pluginOptEscape(obfs.Host))
+	}
+
+	if plugin := proxy.ShadowSocks.V2rayPlugin; plugin != nil {
+		opts := "v2ray-plugin;mode=websocket;host=" + pluginOptEscape(plugin.Host) + ";path=" + pluginOptEscape(plugin.Path)
+		if plugin.TLS {
+			opts += ";tls"
+		}
+		if plugin.SkipCertVerify {
+			opts += ";skip-cert-verify"
+		}
+		if !plugin.Mux {
+			opts += ";mux=0"
+		}
+		for _, header := range joinHeaders(plugin.Headers) {
+			opts += ";header=" + pluginOptEscape(header)
+		}
+		link.Path = "/"
+		link.RawQuery = "plugin=" + url.QueryEscape(opts)
+	}
+
+	return link.String()
+}
+
+// joinHeaders returns headers as sorted "Name: Value" strings
+func joinHeaders(headers map[string]string) []string {
+
+	var joined []string
+
+	for name, value := range headers {
+		joined = append(joined, name+": "+value)
+	}
+
+	sort.Strings(joined)
+	return joined
+}
+
+// pluginOptEscape escapes a SIP003 option value, parsePluginOpts reads it back
+func pluginOptEscape(value string) string {
+
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, "=", `\=`).Replace(value)
+}
+
+func (proxy *Proxy) shadowSocksRLink() string {
+
+	var (
+		ssr      = proxy.ShadowSocksR
+		params   = url.Values{}
+		obfs     = "plain"
+		protocol = "origin"
+	)
+
+	if ssr.Obfs != nil {
+		obfs = ssr.Obfs.Schema
+		if ssr.Obfs.Param != "" {
+			params.Set("obfsparam", util.Base64URLEncode(ssr.Obfs.Param))
+		}
+	}
+
+	if ssr.Protocol != nil {
+		protocol = ssr.Protocol.Schema
+		if ssr.Protocol.Param != "" {
+			params.Set("protoparam", util.Base64URLEncode(ssr.Protocol.Param))
+		}
+	}
+
+	if proxy.Name != "" {
+		params.Set("remarks", util.Base64URLEncode(proxy.Name))
+	}
+
+	value := strings.Join([]string{
+		ssr.Server,
+		strconv.Itoa(ssr.Port),
+		protocol,
+		ssr.Cipher,
+		obfs,
+		util.Base64URLEncode(ssr.Password),
+	}, ":")
+
+	if len(params) > 0 {
+		value += "/?" + params.Encode()
+	}
+
+	return "ssr://" + util.Base64URLEncode(value)
+}
+
+func (proxy *Proxy) vMessLink() (string, error) {
+
+	settings := &protocol.VMessSettings{}
+	if err := json.Unmarshal(proxy.VMess.Settings, settings); err != nil || len(settings.VMessVNext) == 0 || len(settings.VMessVNext[0].Users) == 0 {
+		return "", fmt.Errorf("export: invalid vmess settings")
+	}
+
+	vnext := settings.VMessVNext[0]
+	info := newStreamInfo(proxy.VMess.StreamSettings)
+
+	//v2rayN names http/2 h2 and keeps the quic settings in host and path
+	network := info.Network
+	switch network {
+	case "http":
+		network = "h2"
+	case "quic":
+		info.Host = info.QUICSecurity
+		info.Path = info.QUICKey
+	case "kcp":
+		info.Path = info.Seed
+	case "grpc":
+		info.HeaderType = "gun"
+		info.Path = info.ServiceName
+	}
+
+	//the host doubles as the tls server name
+	if info.Host == "" && network != "quic" {
+		info.Host = info.SNI
+	}
+
+	link := map[string]interface{}{
+		"v":    "2",
+		"ps":   proxy.Name,
+		"add":  vnext.Address,
+		"port": strconv.Itoa(vnext.Port),
+		"id":   vnext.Users[0].ID,
+		"aid":  strconv.Itoa(vnext.Users[0].AlterId),
+		"scy":  vnext.Users[0].Security,
+		"net":  network,
+		"type": info.HeaderType,
+		"host": info.Host,
+		"path": info.Path,
+		"tls":  "",
+		"sni":  info.SNI,
+	}
+
+	if info.Security == "tls" {
+		link["tls"] = "tls"
+	}
+
+	if len(info.ALPN) > 0 {
+		link["alpn"] = strings.Join(info.ALPN, ",")
+	}
+
+	if info.AllowInsecure {
+		link["allowInsecure"] = "1"
+	}
+
+	if experiments := vnext.Users[0].Experiments; experiments != "" {
+		link["experiments"] = experiments
+	}
+
+	//the extras of the stream use the names of the vless query
+	if len(info.Headers) > 0 {
+		link["header"] = info.Headers
+	}
+
+	if info.Method != "" {
+		link["method"] = info.Method
+	}
+
+	if info.MaxEarlyData > 0 {
+		link["maxEarlyData"] = strconv.Itoa(info.MaxEarlyData)
+	}
+
+	if info.EarlyDataHeaderName != "" {
+		link["earlyDataHeaderName"] = info.EarlyDataHeaderName
+	}
+
+	//mux is not part of the v2rayN schema, newVMess reads the same keys
+	mux := url.Values{}
+	setMuxQuery(mux, proxy.VMess.Mux)
+	for key := range mux {
+		link[key] = mux.Get(key)
+	}
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (proxy *Proxy) vLessLink() (string, error) {
+
+	settings := &protocol.VLessSettings{}
+	if err := json.Unmarshal(proxy.VLess.Settings, settings); err != nil || len(settings.VLessVNext) == 0 || len(settings.VLessVNext[0].Users) == 0 {
+		return "", fmt.Errorf("export: invalid vless settings")
+	}
+
+	vnext := settings.VLessVNext[0]
+
+	query := newStreamInfo(proxy.VLess.StreamSettings).query()
+	query.Set("encryption", vnext.Users[0].Encryption)
+	setMuxQuery(query, proxy.VLess.Mux)
+
+	link := &url.URL{
+		Scheme:   "vless",
+		User:     url.User(vnext.Users[0].ID),
+		Host:     net.JoinHostPort(vnext.Address, strconv.Itoa(vnext.Port)),
+		RawQuery: query.Encode(),
+		Fragment: proxy.Name,
+	}
+
+	return link.String(), nil
+}
+
+func (proxy *Proxy) trojanLink() (string, error) {
+
+	settings := &protocol.TrojanSettings{}
+	if err := json.Unmarshal(proxy.Trojan.Settings, settings); err != nil || len(settings.TrojanServers) == 0 {
+		return "", fmt.Errorf("export: invalid trojan settings")
+	}
+
+	server := settings.TrojanServers[0]
+
+	query := newStreamInfo(proxy.Trojan.StreamSettings).query()
+	if proxy.Schema == "TROJAN-GO" && proxy.TrojanGo.Mux {
+		query.Set("mux", "1")
+	} else {
+		setMuxQuery(query, proxy.Trojan.Mux)
+	}
+
+	link := &url.URL{
+		Scheme:   strings.ToLower(proxy.Schema),
+		User:     url.User(server.Password),
+		Host:     net.JoinHostPort(server.Address, strconv.Itoa(server.Port)),
+		RawQuery: query.Encode(),
+		Fragment: proxy.Name,
+	}
+
+	return link.String(), nil
+}
+
+// setMuxQuery adds the mux parameters read back by newMuxSettings
+func setMuxQuery(query url.Values, mux *vdata.MuxSettings) {
+
+	if mux == nil {
+		return
+	}
+
+	if !mux.Enable {
+		query.Set("mux", "0")
+		return
+	}
+
+	query.Set("mux", "1")
+	if mux.Concurrency > 0 {
+		query.Set("muxConcurrency", strconv.Itoa(mux.Concurrency))
+	}
+}
+
+// streamInfo flattens the stream settings into the fields share links use,
+// Headers are the ones besides Host as "Name: Value"
+type streamInfo struct {
+	Network             string
+	HeaderType          string
+	Host                string
+	Path                string
+	Headers             []string
+	Method              string
+	MaxEarlyData        int
+	EarlyDataHeaderName string
+	Seed                string
+	QUICSecurity        string
+	QUICKey             string
+	ServiceName         string
+	Security            string
+	SNI                 string
+	ALPN                []string
+	AllowInsecure       bool
+}
+
+func newStreamInfo(settings *vdata.StreamSettings) *streamInfo {
+
+	info := &streamInfo{
+		Network:    "tcp",
+		HeaderType: "none",
+		Security:   "none",
+	}
+
+	if settings == nil {
+		return info
+	}
+
+	if settings.Network != "" {
+		info.Network = settings.Network
+	}
+
+	switch {
+	case settings.TCPSettings != nil && settings.TCPSettings.TCPHeader != nil:
+		info.HeaderType = settings.TCPSettings.TCPHeader.Type
+		if request := settings.TCPSettings.TCPHeader.Request; request != nil {
+			info.Host = strings.Join(request.Headers["Host"], ",")
+			info.Path = strings.Join(request.Path, ",")
+			info.Method = request.Method
+			names := make([]string, 0, len(request.Headers))
+			for name := range request.Headers {
+				if name != "Host" {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				for _, value := range request.Headers[name] {
+					info.Headers = append(info.Headers, name+": "+value)
+				}
+			}
+		}
+	case settings.KCPSettings != nil:
+		if settings.KCPSettings.Header != nil {
+			info.HeaderType = settings.KCPSettings.Header.Type
+		}
+		info.Seed = settings.KCPSettings.Seed
+	case settings.WSSettings != nil:
+		info.Host = settings.WSSettings.Headers["Host"]
+		info.Path = settings.WSSettings.Path
+		info.MaxEarlyData = settings.WSSettings.MaxEarlyData
+		info.EarlyDataHeaderName = settings.WSSettings.EarlyDataHeaderName
+		for name, value := range settings.WSSettings.Headers {
+			if name != "Host" {
+				info.Headers = append(info.Headers, name+": "+value)
+			}
+		}
+		sort.Strings(info.Headers)
+	case settings.HTTPSettings != nil:
+		info.Host = strings.Join(settings.HTTPSettings.Host, ",")
+		info.Path = settings.HTTPSettings.Path
+	case settings.QUICSettings != nil:
+		if settings.QUICSettings.Header != nil {
+			info.HeaderType = settings.QUICSettings.Header.Type
+		}
+		info.QUICSecurity = settings.QUICSettings.Security
+		info.QUICKey = settings.QUICSettings.Key
+	case settings.GRPCSettings != nil:
+		info.ServiceName = settings.GRPCSettings.ServiceName
+	}
+
+	if settings.Security == "tls" {
+		info.Security = "tls"
+		if settings.TLSSettings != nil {
+			info.SNI = settings.TLSSettings.ServerName
+			info.ALPN = settings.TLSSettings.Alpn
+			info.AllowInsecure = settings.TLSSettings.AllowInsecure
+		}
+	}
+
+	if info.HeaderType == "" {
+		info.HeaderType = "none"
+	}
+
+	return info
+}
+
+// query returns the parameters of the vless and trojan link formats
+func (info *streamInfo) query() url.Values {
+
+	query := url.Values{}
+	query.Set("type", info.Network)
+	query.Set("security", info.Security)
+
+	setIf := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	if info.HeaderType != "none" {
+		query.Set("headerType", info.HeaderType)
+	}
+
+	setIf("host", info.Host)
+	setIf("path", info.Path)
+	setIf("seed", info.Seed)
+	setIf("quicSecurity", info.QUICSecurity)
+	setIf("key", info.QUICKey)
+	setIf("serviceName", info.ServiceName)
+	setIf("sni", info.SNI)
+	setIf("alpn", strings.Join(info.ALPN, ","))
+	setIf("method", info.Method)
+	setIf("earlyDataHeaderName", info.EarlyDataHeaderName)
+
+	if info.MaxEarlyData > 0 {
+		query.Set("maxEarlyData", strconv.Itoa(info.MaxEarlyData))
+	}
+
+	for _, header := range info.Headers {
+		query.Add("header", header)
+	}
+
+	if info.AllowInsecure {
+		query.Set("allowInsecure", "1")
+	}
+
+	return query
+}
+
+func (c *ClashProxy) setStream(settings *vdata.StreamSettings) error {
+
+	if settings == nil {
+		return nil
+	}
+
+	switch settings.Network {
+	case "", "tcp":
+		if settings.TCPSettings != nil && settings.TCPSettings.TCPHeader != nil && settings.TCPSettings.TCPHeader.Type == "http" {
+			c.Network = "http"
+			c.HTTPOpts = &ClashHTTPOpts{}
+			if request := settings.TCPSettings.TCPHeader.Request; request != nil {
+				c.HTTPOpts.Method = request.Method
+				c.HTTPOpts.Path = request.Path
+				c.HTTPOpts.Headers = request.Headers
+			}
+		}
+	case "ws":
+		c.Network = "ws"
+		if ws := settings.WSSettings; ws != nil {
+			c.WSOpts = &ClashWSOpts{
+				Path:                ws.Path,
+				Headers:             ws.Headers,
+				MaxEarlyData:        ws.MaxEarlyData,
+				EarlyDataHeaderName: ws.EarlyDataHeaderName,
+			}
+		}
+	case "http":
+		c.Network = "h2"
+		if h2 := settings.HTTPSettings; h2 != nil {
+			c.H2Opts = &ClashH2Opts{
+				Host: h2.Host,
+				Path: h2.Path,
+			}
+		}
+	case "grpc":
+		c.Network = "grpc"
+		if grpc := settings.GRPCSettings; grpc != nil {
+			c.GrpcOpts = &ClashGrpcOpts{
+				ServiceName: grpc.ServiceName,
+			}
+		}
+	default:
+		return fmt.Errorf("export: network \"%s\" is not supported by clash", settings.Network)
+	}
+
+	if settings.Security == "tls" {
+		c.TLS = true
+		if settings.TLSSettings != nil {
+			c.ServerName = settings.TLSSettings.ServerName
+			c.SkipCertVerify = settings.TLSSettings.AllowInsecure
+			c.ALPN = settings.TLSSettings.Alpn
+		}
+	}
+
+	return nil
+}
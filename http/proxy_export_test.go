@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const testUUID = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+// assertSameProxy compares what the dialers of both proxies use
+func assertSameProxy(t *testing.T, want, got *Proxy) {
+
+	t.Helper()
+
+	if want.Schema != got.Schema || want.Name != got.Name || want.Server != got.Server || want.Port != got.Port {
+		t.Fatalf("proxy %s %s %s:%d, want %s %s %s:%d", got.Schema, got.Name, got.Server, got.Port, want.Schema, want.Name, want.Server, want.Port)
+	}
+
+	for _, pair := range [][2]interface{}{
+		{want.VMess, got.VMess},
+		{want.VLess, got.VLess},
+		{want.Trojan, got.Trojan},
+		{want.TrojanGo, got.TrojanGo},
+		{want.ShadowSocks, got.ShadowSocks},
+		{want.ShadowSocksR, got.ShadowSocksR},
+		{want.HTTPS, got.HTTPS},
+	} {
+		wantJSON, _ := json.Marshal(pair[0])
+		gotJSON, _ := json.Marshal(pair[1])
+		if string(wantJSON) != string(gotJSON) {
+			t.Fatalf("settings differ:\n got %s\nwant %s", gotJSON, wantJSON)
+		}
+	}
+
+	if want.User != got.User || want.Pass != got.Pass || want.Auth != got.Auth {
+		t.Fatalf("credentials differ")
+	}
+}
+
+// roundTrip parses the share link of proxy back into a proxy
+func roundTrip(t *testing.T, proxy *Proxy) *Proxy {
+
+	t.Helper()
+
+	link, err := proxy.ShareLink()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := NewProxy(link)
+	if err != nil {
+		t.Fatalf("%s: %v", link, err)
+	}
+
+	assertSameProxy(t, proxy, parsed)
+
+	return parsed
+}
+
+func TestShareLinkRoundTrip(t *testing.T) {
+
+	vmess, _ := json.Marshal(map[string]interface{}{
+		"v":                   "2",
+		"ps":                  "vmess",
+		"add":                 "example.com",
+		"port":                "443",
+		"id":                  testUUID,
+		"aid":                 "0",
+		"net":                 "ws",
+		"host":                "cdn.example.com",
+		"path":                "/ws",
+		"tls":                 "tls",
+		"alpn":                "h2,http/1.1",
+		"header":              []string{"User-Agent: r4scan"},
+		"maxEarlyData":        2048,
+		"earlyDataHeaderName": "Sec-WebSocket-Protocol",
+	})
+
+	plugin := "v2ray-plugin;mode=websocket;host=cdn.example.com;path=/ws;tls;skip-cert-verify;header=User-Agent: r4\\;scan"
+
+	tests := []struct {
+		link  string
+		check func(proxy *Proxy) bool
+	}{
+		{
+			"vmess://" + base64.StdEncoding.EncodeToString(vmess),
+			func(proxy *Proxy) bool {
+				ws := proxy.VMess.StreamSettings.WSSettings
+				return ws.Headers["User-Agent"] == "r4scan" && ws.Headers["Host"] == "cdn.example.com" &&
+					ws.MaxEarlyData == 2048 && ws.EarlyDataHeaderName == "Sec-WebSocket-Protocol" &&
+					len(proxy.VMess.StreamSettings.TLSSettings.Alpn) == 2
+			},
+		},
+		{
+			"vless://" + testUUID + "@example.com:443?type=ws&security=tls&host=cdn.example.com&path=%2Fws&alpn=h2,http%2F1.1" +
+				"&allowInsecure=true&header=User-Agent:%20r4scan&maxEarlyData=2048&earlyDataHeaderName=Sec-WebSocket-Protocol#vless",
+			func(proxy *Proxy) bool {
+				ws := proxy.VLess.StreamSettings.WSSettings
+				tls := proxy.VLess.StreamSettings.TLSSettings
+				return ws.Headers["User-Agent"] == "r4scan" && ws.MaxEarlyData == 2048 &&
+					ws.EarlyDataHeaderName == "Sec-WebSocket-Protocol" && len(tls.Alpn) == 2 && tls.AllowInsecure
+			},
+		},
+		{
+			"trojan://pass@example.com:443?type=ws&path=%2Fws&alpn=h2&header=User-Agent:%20r4scan&maxEarlyData=2048#trojan",
+			func(proxy *Proxy) bool {
+				ws := proxy.Trojan.StreamSettings.WSSettings
+				return ws.Headers["User-Agent"] == "r4scan" && ws.MaxEarlyData == 2048 &&
+					reflect.DeepEqual(proxy.Trojan.StreamSettings.TLSSettings.Alpn, []string{"h2"})
+			},
+		},
+		{
+			"trojan-go://pass@example.com:443?alpn=http%2F1.1&allowInsecure=1#trojan-go",
+			func(proxy *Proxy) bool {
+				return reflect.DeepEqual(proxy.TrojanGo.ALPN, []string{"http/1.1"}) && proxy.TrojanGo.AllowInsecure
+			},
+		},
+		{
+			"ss://" + base64.RawURLEncoding.EncodeToString([]byte("aes-128-gcm:pass")) + "@example.com:8388/?plugin=" + url.QueryEscape(plugin) + "#ss",
+			func(proxy *Proxy) bool {
+				plugin := proxy.ShadowSocks.V2rayPlugin
+				return plugin.Host == "cdn.example.com" && plugin.Path == "/ws" && plugin.TLS && plugin.SkipCertVerify &&
+					plugin.Headers["User-Agent"] == "r4;scan"
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		proxy, err := NewProxy(test.link)
+		if err != nil {
+			t.Fatalf("%s: %v", test.link, err)
+		}
+
+		if !test.check(proxy) {
+			t.Fatalf("%s: settings not parsed", test.link)
+		}
+
+		if !test.check(roundTrip(t, proxy)) {
+			t.Fatalf("%s: settings lost by the share link", test.link)
+		}
+	}
+}
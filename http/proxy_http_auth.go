@@ -0,0 +1,332 @@
+package http
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/Azure/go-ntlmssp"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// httpAuth answers the 407 challenges of an http proxy. The first challenge
+// picks the scheme, NTLM takes one more round trip on the same connection.
+// challenge is the one last answered, preempted is set while an answer to a
+// cached challenge waits for the proxy.
+type httpAuth struct {
+	user       string
+	pass       string
+	method     string
+	uri        string
+	cnonce     string
+	scheme     string
+	challenge  httpChallenge
+	challenged bool
+	preempted  bool
+}
+
+// httpAuthCache remembers the challenge a proxy accepted, so the following
+// CONNECTs skip the 407 picking the scheme
+type httpAuthCache struct {
+	mu        sync.Mutex
+	challenge *httpChallenge
+}
+
+// httpChallenge is a Proxy-Authenticate header split into the scheme and the
+// rest, a token for NTLM or the parameters for Digest
+type httpChallenge struct {
+	scheme string
+	value  string
+}
+
+func newHTTPChallenge(header string) httpChallenge {
+
+	scheme, value, _ := strings.Cut(strings.TrimSpace(header), " ")
+
+	return httpChallenge{
+		scheme: scheme,
+		value:  strings.TrimSpace(value),
+	}
+}
+
+// basic is only sent when the proxy offers nothing else, the password goes
+// to the proxy in the clear
+func (auth *httpAuth) basic() string {
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.user+":"+auth.pass))
+}
+
+// preempt answers a cached challenge before the proxy sends one, NTLM starts
+// with its negotiate message
+func (auth *httpAuth) preempt(challenge httpChallenge) (string, error) {
+
+	auth.scheme = challenge.scheme
+	auth.challenge = challenge
+	auth.preempted = true
+
+	switch strings.ToLower(challenge.scheme) {
+	case "basic":
+		return auth.basic(), nil
+	case "digest":
+		return auth.digest(challenge.value)
+	default:
+		return auth.ntlmNegotiate()
+	}
+}
+
+// restartable reports whether the answer to the next challenge may go on a
+// new connection, ntlm binds its rounds to one
+func (auth *httpAuth) restartable() bool {
+
+	switch strings.ToLower(auth.scheme) {
+	case "":
+		return true
+	case "ntlm", "negotiate":
+		return false
+	default:
+		return auth.preempted
+	}
+}
+
+// next returns the Proxy-Authorization answering challenges
+func (auth *httpAuth) next(challenges []httpChallenge) (string, error) {
+
+	switch strings.ToLower(auth.scheme) {
+	case "":
+
+		//ntlm is preferred as it does not expose the password to the proxy
+		for _, preferred := range []string{"ntlm", "negotiate", "digest", "basic"} {
+			for _, challenge := range challenges {
+				if strings.ToLower(challenge.scheme) != preferred {
+					continue
+				}
+
+				auth.scheme = challenge.scheme
+				auth.challenge = challenge
+				switch preferred {
+				case "digest":
+					return auth.digest(challenge.value)
+				case "basic":
+					return auth.basic(), nil
+				}
+				return auth.ntlmNegotiate()
+			}
+		}
+
+		return "", fmt.Errorf("proxy authentication failed")
+
+	case "ntlm", "negotiate":
+
+		auth.preempted = false
+
+		if auth.challenged {
+			return "", fmt.Errorf("proxy authentication failed")
+		}
+
+		auth.challenged = true
+
+		for _, challenge := range challenges {
+			if strings.EqualFold(challenge.scheme, auth.scheme) && challenge.value != "" {
+				return auth.ntlmAuthenticate(challenge.value)
+			}
+		}
+
+		return "", fmt.Errorf("proxy authentication failed: missing ntlm challenge")
+
+	case "basic":
+
+		return "", fmt.Errorf("proxy authentication failed")
+
+	default:
+
+		if auth.challenged {
+			return "", fmt.Errorf("proxy authentication failed")
+		}
+
+		//a cached nonce may have expired, a stale nonce is the only other
+		//reason to answer digest twice
+		preempted := auth.preempted
+		auth.preempted = false
+
+		for _, challenge := range challenges {
+			if strings.EqualFold(challenge.scheme, auth.scheme) && (preempted || strings.EqualFold(parseAuthParams(challenge.value)["stale"], "true")) {
+				auth.challenge = challenge
+				auth.challenged = !preempted
+				return auth.digest(challenge.value)
+			}
+		}
+
+		return "", fmt.Errorf("proxy authentication failed")
+	}
+}
+
+func (auth *httpAuth) ntlmNegotiate() (string, error) {
+
+	_, domain, _ := ntlmssp.GetDomain(auth.user)
+
+	message, err := ntlmssp.NewNegotiateMessage(domain, "")
+	if err != nil {
+		return "", err
+	}
+
+	return auth.scheme + " " + base64.StdEncoding.EncodeToString(message), nil
+}
+
+func (auth *httpAuth) ntlmAuthenticate(token string) (string, error) {
+
+	challenge, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("proxy authentication failed: invalid ntlm challenge")
+	}
+
+	user, _, domainNeeded := ntlmssp.GetDomain(auth.user)
+
+	message, err := ntlmssp.ProcessChallenge(challenge, user, auth.pass, domainNeeded)
+	if err != nil {
+		return "", fmt.Errorf("proxy authentication failed: %v", err)
+	}
+
+	return auth.scheme + " " + base64.StdEncoding.EncodeToString(message), nil
+}
+
+// digest answers a challenge as RFC 7616 describes, the uri of CONNECT is the
+// authority of the target. A random cnonce is drawn unless one is already set.
+func (auth *httpAuth) digest(value string) (string, error) {
+
+	var (
+		params    = parseAuthParams(value)
+		algorithm = params["algorithm"]
+		session   = strings.HasSuffix(strings.ToUpper(algorithm), "-SESS")
+		newHash   func() hash.Hash
+	)
+
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("proxy authentication failed: unsupported digest algorithm \"%s\"", algorithm)
+	}
+
+	digest := func(values ...string) string {
+		h := newHash()
+		h.Write([]byte(strings.Join(values, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	if auth.cnonce == "" {
+		cnonce := make([]byte, 8)
+		if _, err := rand.Read(cnonce); err != nil {
+			return "", err
+		}
+		auth.cnonce = hex.EncodeToString(cnonce)
+	}
+	cnonce := auth.cnonce
+
+	ha1 := digest(auth.user, params["realm"], auth.pass)
+	if session {
+		ha1 = digest(ha1, params["nonce"], cnonce)
+	}
+	ha2 := digest(auth.method, auth.uri)
+
+	header := fmt.Sprintf("Digest username=\"%s\", realm=\"%s\", nonce=\"%s\", uri=\"%s\"",
+		auth.user, params["realm"], params["nonce"], auth.uri)
+
+	if qop, exist := params["qop"]; exist {
+		supported := false
+		for _, option := range strings.Split(qop, ",") {
+			if strings.TrimSpace(option) == "auth" {
+				supported = true
+			}
+		}
+		if !supported {
+			return "", fmt.Errorf("proxy authentication failed: unsupported digest qop \"%s\"", qop)
+		}
+
+		const nc = "00000001"
+		header += fmt.Sprintf(", qop=auth, nc=%s, cnonce=\"%s\", response=\"%s\"",
+			nc, cnonce, digest(ha1, params["nonce"], nc, cnonce, "auth", ha2))
+	} else {
+		header += fmt.Sprintf(", response=\"%s\"", digest(ha1, params["nonce"], ha2))
+	}
+
+	if algorithm != "" {
+		header += ", algorithm=" + algorithm
+	}
+
+	if opaque, exist := params["opaque"]; exist {
+		header += fmt.Sprintf(", opaque=\"%s\"", opaque)
+	}
+
+	return header, nil
+}
+
+// parseAuthParams splits the comma separated key=value pairs of a challenge,
+// quoted values may contain commas
+func parseAuthParams(value string) map[string]string {
+
+	params := map[string]string{}
+
+	for value != "" {
+		var key, param string
+
+		key, value, _ = strings.Cut(value, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, "\"") {
+			var quoted strings.Builder
+			i := 1
+			for ; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+				}
+				quoted.WriteByte(value[i])
+			}
+			param = quoted.String()
+			_, value, _ = strings.Cut(value[i:], ",")
+		} else {
+			param, value, _ = strings.Cut(value, ",")
+			param = strings.TrimSpace(param)
+		}
+
+		if key != "" {
+			params[key] = param
+		}
+	}
+
+	return params
+}
+
+func (c *httpAuthCache) load() (httpChallenge, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.challenge == nil {
+		return httpChallenge{}, false
+	}
+
+	return *c.challenge, true
+}
+
+func (c *httpAuthCache) store(challenge httpChallenge) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.challenge = &challenge
+}
+
+func (c *httpAuthCache) reset() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.challenge = nil
+}
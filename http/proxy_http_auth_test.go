@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// TestHTTPAuthDigest answers the challenges of the RFC 7616 section 3.9 example
+func TestHTTPAuthDigest(t *testing.T) {
+
+	cases := []struct {
+		algorithm string
+		response  string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, c := range cases {
+		auth := &httpAuth{
+			user:   "Mufasa",
+			pass:   "Circle of Life",
+			method: "GET",
+			uri:    "/dir/index.html",
+			cnonce: "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ",
+		}
+
+		challenge := newHTTPChallenge(`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=` + c.algorithm +
+			`, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`)
+
+		authorization, err := auth.next([]httpChallenge{challenge})
+		if err != nil {
+			t.Fatalf("%s: %v", c.algorithm, err)
+		}
+
+		scheme := newHTTPChallenge(authorization)
+		if scheme.scheme != "Digest" {
+			t.Fatalf("%s: scheme %q", c.algorithm, scheme.scheme)
+		}
+
+		params := parseAuthParams(scheme.value)
+		if params["response"] != c.response {
+			t.Errorf("%s: response %s, want %s", c.algorithm, params["response"], c.response)
+		}
+		if params["nc"] != "00000001" || params["qop"] != "auth" || params["opaque"] != "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS" {
+			t.Errorf("%s: authorization %s", c.algorithm, authorization)
+		}
+	}
+}
+
+// TestHTTPAuthNTLM runs the negotiate and authenticate rounds against a
+// minimal NTLMv2 challenge
+func TestHTTPAuthNTLM(t *testing.T) {
+
+	auth := &httpAuth{user: `EXAMPLE\Mufasa`, pass: "Circle of Life", method: "CONNECT", uri: "example.com:443"}
+
+	authorization, err := auth.next([]httpChallenge{newHTTPChallenge("Basic realm=\"proxy\""), newHTTPChallenge("NTLM")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message := ntlmMessage(t, authorization); message[8] != 1 {
+		t.Fatalf("negotiate: message type %d", message[8])
+	}
+
+	//signature, type 2, empty target name, unicode | ntlm | extended session
+	//security, server challenge, reserved, empty target info
+	challenge := []byte("NTLMSSP\x00\x02\x00\x00\x00\x00\x00\x00\x00\x30\x00\x00\x00\x01\x82\x08\x00" +
+		"\x01\x23\x45\x67\x89\xab\xcd\xef\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x30\x00\x00\x00")
+
+	authorization, err = auth.next([]httpChallenge{newHTTPChallenge("NTLM " + base64.StdEncoding.EncodeToString(challenge))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := ntlmMessage(t, authorization)
+	if message[8] != 3 {
+		t.Fatalf("authenticate: message type %d", message[8])
+	}
+
+	//the user name field follows the header and three other fields
+	length, offset := binary.LittleEndian.Uint16(message[36:]), binary.LittleEndian.Uint32(message[40:])
+	if user := message[offset : offset+uint32(length)]; !bytes.Equal(user, []byte("M\x00u\x00f\x00a\x00s\x00a\x00")) {
+		t.Errorf("authenticate: user %q", user)
+	}
+
+	if _, err = auth.next([]httpChallenge{newHTTPChallenge("NTLM")}); err == nil {
+		t.Error("a third ntlm round was answered")
+	}
+}
+
+func ntlmMessage(t *testing.T, authorization string) []byte {
+
+	t.Helper()
+
+	scheme := newHTTPChallenge(authorization)
+	if scheme.scheme != "NTLM" {
+		t.Fatalf("scheme %q", scheme.scheme)
+	}
+
+	message, err := base64.StdEncoding.DecodeString(scheme.value)
+	if err != nil || len(message) < 12 || !bytes.HasPrefix(message, []byte("NTLMSSP\x00")) {
+		t.Fatalf("invalid ntlm message %q", scheme.value)
+	}
+
+	return message
+}
@@ -2,63 +2,228 @@ package http
 
 import (
 	"bufio"
-	"encoding/base64"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/valyala/fasthttp"
 	"net"
+	"net/url"
+	"r4scan/util"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// HTTPS is the tls session to an https proxy, CONNECT goes inside it. CA is a
+// pem file trusted instead of the system roots.
+type HTTPS struct {
+	SNI           string
+	AllowInsecure bool
+	CA            string
+	rootCAs       *x509.CertPool
+}
+
+// errHTTPAuthRedial is returned when the proxy closes the connection after
+// the 407 picking the scheme
+var errHTTPAuthRedial = errors.New("proxy closed the connection during authentication")
+
 func (proxy *Proxy) HTTPDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	return func(addr string) (net.Conn, error) {
+	return FastHTTPDialer(context.Background(), timeout, proxy.httpDialer(directDialContext))
+}
+
+func (proxy *Proxy) httpDialer(dial DialContextFunc) DialContextFunc {
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 
 		var (
-			request  string
-			response *fasthttp.Response
-			conn     net.Conn
-			err      error
+			auth          *httpAuth
+			authorization string
+			err           error
 		)
 
-		proxyAddr := net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port))
+		//the first CONNECT goes without credentials and the 407 tells which
+		//scheme the proxy accepts, later ones answer that scheme up front
+		if proxy.Auth {
+			auth = &httpAuth{user: proxy.User, pass: proxy.Pass, method: "CONNECT", uri: addr}
+			if challenge, ok := proxy.httpAuth.load(); ok {
+				if authorization, err = auth.preempt(challenge); err != nil {
+					return nil, err
+				}
+			}
+		}
 
-		if timeout == 0 {
-			conn, err = fasthttp.DialDualStack(proxyAddr)
-		} else {
-			conn, err = fasthttp.DialDualStackTimeout(proxyAddr, timeout)
+		conn, authorization, err := proxy.httpTunnel(ctx, dial, network, addr, auth, authorization)
+
+		//the 407 picking the scheme may close the connection, the exchange
+		//starts over once on a new one
+		if err == errHTTPAuthRedial {
+			conn, _, err = proxy.httpTunnel(ctx, dial, network, addr, auth, authorization)
 		}
 
-		if err != nil {
-			return nil, err
+		if auth != nil {
+			if err != nil {
+				proxy.httpAuth.reset()
+			} else if auth.scheme != "" {
+				proxy.httpAuth.store(auth.challenge)
+			}
 		}
 
-		request = fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
-		if proxy.Auth {
-			auth := base64.StdEncoding.EncodeToString([]byte(proxy.User + ":" + proxy.Pass))
-			request += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", auth)
+		return conn, err
+	}
+}
+
+// httpTunnel dials the proxy and sends CONNECT with authorization, the next
+// one is returned along with errHTTPAuthRedial
+func (proxy *Proxy) httpTunnel(ctx context.Context, dial DialContextFunc, network, addr string, auth *httpAuth, authorization string) (net.Conn, string, error) {
+
+	proxyAddr := net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port))
+
+	conn, err := dial(ctx, network, proxyAddr)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	tunnel := conn
+
+	if err = handshake(ctx, conn, func() error {
+
+		if proxy.Schema == "HTTPS" {
+			tlsConn := tls.Client(conn, &tls.Config{
+				ServerName:         proxy.HTTPS.SNI,
+				InsecureSkipVerify: proxy.HTTPS.AllowInsecure,
+				RootCAs:            proxy.HTTPS.rootCAs,
+			})
+
+			if err := tlsConn.Handshake(); err != nil {
+				return err
+			}
+
+			tunnel = tlsConn
+		}
+
+		tunnel, authorization, err = proxy.httpConnect(tunnel, addr, auth, authorization)
+		return err
+	}); err != nil {
+		return nil, authorization, err
+	}
+
+	return tunnel, "", nil
+}
+
+// httpConnect returns the tunnel once the proxy accepts CONNECT, bytes the
+// proxy sent along with the response are read from it first
+func (proxy *Proxy) httpConnect(conn net.Conn, addr string, auth *httpAuth, authorization string) (net.Conn, string, error) {
+
+	var (
+		reader   = bufio.NewReader(conn)
+		response *fasthttp.Response
+		err      error
+	)
+
+	response = fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+
+	//every 407 is answered on the same connection until the proxy accepts or
+	//the scheme runs out of rounds
+	for {
+		request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+		if authorization != "" {
+			request += fmt.Sprintf("Proxy-Authorization: %s\r\n", authorization)
 		}
 		request += "\r\n"
 
 		if _, err = conn.Write([]byte(request)); err != nil {
-			return nil, err
+			return nil, "", err
+		}
+
+		response.Reset()
+		if err = response.Header.Read(reader); err != nil {
+			return nil, "", err
 		}
 
-		response = fasthttp.AcquireResponse()
-		defer fasthttp.ReleaseResponse(response)
+		if status := response.StatusCode(); status/100 == 2 {
+			if reader.Buffered() > 0 {
+				return &bufferedConn{Conn: conn, reader: reader}, "", nil
+			}
+			return conn, "", nil
+		} else if status != fasthttp.StatusProxyAuthRequired || auth == nil {
+			return nil, "", fmt.Errorf("could not connect to proxy: %s", proxy.String())
+		}
+
+		var challenges []httpChallenge
+		response.Header.VisitAll(func(key, value []byte) {
+			if strings.EqualFold(string(key), "Proxy-Authenticate") {
+				challenges = append(challenges, newHTTPChallenge(string(value)))
+			}
+		})
 
-		response.SkipBody = true
+		restartable := auth.restartable()
 
-		if err = response.Read(bufio.NewReader(conn)); err != nil {
-			conn.Close()
-			return nil, err
+		if authorization, err = auth.next(challenges); err != nil {
+			return nil, "", fmt.Errorf("%v: %s", err, proxy.String())
 		}
 
-		if response.StatusCode() != 200 {
-			conn.Close()
-			return nil, fmt.Errorf("could not connect to proxy: %s", proxy.String())
+		//a body without a length runs until the connection closes
+		if response.Header.ConnectionClose() || response.Header.ContentLength() == -2 {
+			if restartable {
+				return nil, authorization, errHTTPAuthRedial
+			}
+			return nil, "", fmt.Errorf("proxy closed the connection during authentication: %s", proxy.String())
 		}
 
-		return conn, nil
+		//the next response follows the body of the 407
+		if err = response.ReadBody(reader, 0); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// newHTTPS reads the tls options of an https link: sni defaults to the server,
+// allowInsecure skips the verification and ca is the path of a pem file
+func newHTTPS(urls *url.URL, server string) (https HTTPS, err error) {
+
+	query := urls.Query()
+
+	https.SNI = strings.TrimSpace(query.Get("sni"))
+	if https.SNI == "" {
+		https.SNI = server
+	}
+
+	if value := query.Get("allowInsecure"); value != "" {
+		if https.AllowInsecure, err = strconv.ParseBool(value); err != nil {
+			return https, fmt.Errorf("parse error: invalid allowInsecure \"%s\"", value)
+		}
 	}
+
+	if https.CA = strings.TrimSpace(query.Get("ca")); https.CA != "" {
+		if https.rootCAs, err = util.LoadCertPool(https.CA); err != nil {
+			return https, fmt.Errorf("parse error: %v", err)
+		}
+	}
+
+	return https, nil
+}
+
+// query is the inverse of newHTTPS, options at their default are left out
+func (https *HTTPS) query(server string) url.Values {
+
+	query := url.Values{}
+
+	if https.SNI != server {
+		query.Set("sni", https.SNI)
+	}
+
+	if https.AllowInsecure {
+		query.Set("allowInsecure", "1")
+	}
+
+	if https.CA != "" {
+		query.Set("ca", https.CA)
+	}
+
+	return query
 }
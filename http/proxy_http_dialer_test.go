@@ -0,0 +1,236 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHTTPDialerChallenge answers a digest 407 on the same connection and
+// keeps the bytes the proxy sent right after accepting CONNECT
+func TestHTTPDialerChallenge(t *testing.T) {
+
+	conn := dialHTTPProxy(t, func(conn net.Conn, reader *bufio.Reader) error {
+
+		request, err := nethttp.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+		if header := request.Header.Get("Proxy-Authorization"); header != "" {
+			return fmt.Errorf("credentials sent before the challenge: %s", header)
+		}
+
+		if _, err = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n"+
+			"Proxy-Authenticate: Basic realm=\"proxy\"\r\n"+
+			"Proxy-Authenticate: Digest realm=\"proxy\", nonce=\"abc\", qop=\"auth\"\r\n"+
+			"Content-Length: 4\r\n\r\ndeny"); err != nil {
+			return err
+		}
+
+		if request, err = nethttp.ReadRequest(reader); err != nil {
+			return err
+		}
+		if header := request.Header.Get("Proxy-Authorization"); !strings.HasPrefix(header, "Digest ") {
+			return fmt.Errorf("digest expected, got %q", header)
+		}
+
+		_, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\nhello")
+		return err
+	})
+
+	if greeting := readGreeting(t, conn); greeting != "hello" {
+		t.Errorf("greeting %q", greeting)
+	}
+}
+
+// TestHTTPDialerRedial starts over on a new connection when the 407 picking
+// the scheme closes the first one
+func TestHTTPDialerRedial(t *testing.T) {
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	conn := dialHTTPProxy(t, func(conn net.Conn, reader *bufio.Reader) error {
+
+		if _, err := nethttp.ReadRequest(reader); err != nil {
+			return err
+		}
+
+		_, err := io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n"+
+			"Proxy-Authenticate: Basic realm=\"proxy\"\r\nConnection: close\r\n\r\n")
+		return err
+
+	}, func(conn net.Conn, reader *bufio.Reader) error {
+
+		request, err := nethttp.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+		if header := request.Header.Get("Proxy-Authorization"); header != basic {
+			return fmt.Errorf("basic expected, got %q", header)
+		}
+
+		_, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\nhello")
+		return err
+	})
+
+	if greeting := readGreeting(t, conn); greeting != "hello" {
+		t.Errorf("greeting %q", greeting)
+	}
+}
+
+// TestHTTPDialerCache answers the scheme a proxy accepted up front on the
+// following dials
+func TestHTTPDialerCache(t *testing.T) {
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	accept := func(conn net.Conn, reader *bufio.Reader) error {
+
+		request, err := nethttp.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+
+		if header := request.Header.Get("Proxy-Authorization"); header != basic {
+			_, err = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n"+
+				"Proxy-Authenticate: Basic realm=\"proxy\"\r\nContent-Length: 0\r\n\r\n")
+			if err != nil {
+				return err
+			}
+			if request, err = nethttp.ReadRequest(reader); err != nil {
+				return err
+			}
+			if header = request.Header.Get("Proxy-Authorization"); header != basic {
+				return fmt.Errorf("basic expected, got %q", header)
+			}
+		}
+
+		_, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return err
+	}
+
+	proxy := newTestHTTPProxy(t)
+	proxy.dial(accept)
+
+	//a second 407 would fail the handler
+	proxy.dial(func(conn net.Conn, reader *bufio.Reader) error {
+
+		request, err := nethttp.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+		if header := request.Header.Get("Proxy-Authorization"); header != basic {
+			return fmt.Errorf("cached basic expected, got %q", header)
+		}
+
+		_, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return err
+	})
+}
+
+// testHTTPProxy serves one connection per handler of every dial, the proxy
+// keeps its state across dials
+type testHTTPProxy struct {
+	t        *testing.T
+	listener net.Listener
+	proxy    *Proxy
+}
+
+func newTestHTTPProxy(t *testing.T) *testHTTPProxy {
+
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	proxy, err := NewProxy("http://user:pass@" + listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testHTTPProxy{t: t, listener: listener, proxy: proxy}
+}
+
+// dialHTTPProxy connects through a new proxy and fails the test when a
+// handler does
+func dialHTTPProxy(t *testing.T, handlers ...func(conn net.Conn, reader *bufio.Reader) error) net.Conn {
+
+	t.Helper()
+
+	return newTestHTTPProxy(t).dial(handlers...)
+}
+
+func (p *testHTTPProxy) dial(handlers ...func(conn net.Conn, reader *bufio.Reader) error) net.Conn {
+
+	p.t.Helper()
+
+	served := make(chan error, 1)
+
+	go func() {
+		for i, handler := range handlers {
+			conn, err := p.listener.Accept()
+			if err != nil {
+				served <- err
+				return
+			}
+
+			err = handler(conn, bufio.NewReader(conn))
+
+			//the last connection stays open as the tunnel
+			if err != nil || i < len(handlers)-1 {
+				conn.Close()
+			}
+
+			if err != nil {
+				served <- err
+				return
+			}
+		}
+		served <- nil
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	conn, dialErr := p.proxy.httpDialer(directDialContext)(ctx, "tcp", "example.com:443")
+
+	//a failing handler explains the dial error
+	if dialErr != nil {
+		p.listener.Close()
+	}
+	if err := <-served; err != nil {
+		p.t.Fatal(err)
+	}
+	if dialErr != nil {
+		p.t.Fatal(dialErr)
+	}
+
+	p.t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func readGreeting(t *testing.T, conn net.Conn) string {
+
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	greeting := make([]byte, 5)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Fatal(err)
+	}
+
+	return string(greeting)
+}
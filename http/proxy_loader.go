@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"r4scan/util"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ProxyLoadError reports a proxy entry that could not be parsed
+type ProxyLoadError struct {
+	Source string
+	Line   int
+	Err    error
+}
+
+func (e *ProxyLoadError) Error() string {
+
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Source, e.Line, e.Err)
+}
+
+func LoadProxyFile(file string) ([]*Proxy, []error) {
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, []error{&ProxyLoadError{Source: file, Err: err}}
+	}
+
+	return ParseProxyList(file, data)
+}
+
+// LoadProxyURL downloads a proxy list or a base64 subscription
+func LoadProxyURL(rawUrl string, timeout time.Duration) ([]*Proxy, []error) {
+
+	client := NewClient().
+		SetMethod("GET").
+		SetRetry(1).
+		SetTimeout(timeout)
+
+	response, err := client.Do(rawUrl)
+	defer ReleaseResponse(response)
+
+	if err != nil {
+		return nil, []error{&ProxyLoadError{Source: rawUrl, Err: err}}
+	}
+
+	if response.StatusCode() != 200 {
+		return nil, []error{&ProxyLoadError{Source: rawUrl, Err: fmt.Errorf("invalid status code: %d", response.StatusCode())}}
+	}
+
+	body, err := response.BodyUncompressed()
+	if err != nil {
+		body = response.Body()
+	}
+
+	return ParseProxyList(rawUrl, body)
+}
+
+var clashProfile = regexp.MustCompile(`(?m)^proxies:`)
+
+// ParseProxyList parses one proxy link per line. A body without any link is
+// decoded as a base64 subscription first, Clash profiles are detected by
+// their proxies section.
+func ParseProxyList(source string, data []byte) (proxies []*Proxy, errs []error) {
+
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	if clashProfile.Match(data) {
+		return ParseClash(source, data)
+	}
+
+	if len(data) > 0 && !bytes.Contains(data, []byte("://")) {
+
+		//subscriptions may be wrapped over several lines
+		value, err := util.Base64URLDecode(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return nil, []error{&ProxyLoadError{Source: source, Err: fmt.Errorf("neither a proxy list nor a base64 subscription")}}
+		}
+
+		data = []byte(value)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		proxy, err := NewProxy(text)
+		if err != nil {
+			errs = append(errs, &ProxyLoadError{Source: source, Line: line, Err: err})
+			continue
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, &ProxyLoadError{Source: source, Line: line + 1, Err: err})
+	}
+
+	return
+}
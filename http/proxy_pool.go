@@ -0,0 +1,359 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProxyPool spreads connections over several proxies. The Selector decides
+// which proxy handles each new connection.
+type ProxyPool struct {
+	proxies  []*PoolProxy
+	selector Selector
+	chain    *ProxyChain
+}
+
+type PoolProxy struct {
+	Proxy   *Proxy
+	latency int64
+}
+
+type Selector interface {
+	Select(addr string, proxies []*PoolProxy) *PoolProxy
+}
+
+// FailureSelector is implemented by selectors that react to failed dials
+type FailureSelector interface {
+	Failed(addr string, p *PoolProxy)
+}
+
+var SelectorList = map[string]func() Selector{
+	"round-robin": func() Selector { return &RoundRobinSelector{} },
+	"random":      func() Selector { return &RandomSelector{} },
+	"latency":     func() Selector { return &LatencySelector{} },
+	"sticky":      func() Selector { return &StickySelector{} },
+}
+
+func NewProxyPool(proxies []*Proxy) *ProxyPool {
+
+	pool := &ProxyPool{
+		selector: &RoundRobinSelector{},
+	}
+
+	for _, proxy := range proxies {
+		pool.proxies = append(pool.proxies, &PoolProxy{Proxy: proxy})
+	}
+
+	return pool
+}
+
+func (pool *ProxyPool) SetSelector(selector Selector) *ProxyPool {
+
+	pool.selector = selector
+	return pool
+}
+
+// SetChain reaches every proxy of the pool through the chain
+func (pool *ProxyPool) SetChain(chain *ProxyChain) *ProxyPool {
+
+	pool.chain = chain
+	return pool
+}
+
+func (pool *ProxyPool) SetStrategy(strategy string) (*ProxyPool, error) {
+
+	newSelector, exist := SelectorList[strategy]
+	if !exist {
+		return pool, fmt.Errorf("invalid proxy strategy \"%s\"", strategy)
+	}
+
+	pool.selector = newSelector()
+	return pool, nil
+}
+
+func (pool *ProxyPool) Len() int {
+
+	return len(pool.proxies)
+}
+
+func (pool *ProxyPool) Proxies() []*PoolProxy {
+
+	return pool.proxies
+}
+
+// Close shuts down the proxies of the pool and its chain
+func (pool *ProxyPool) Close() error {
+
+	var err error
+
+	for _, p := range pool.proxies {
+		if closeErr := p.Proxy.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	if pool.chain != nil {
+		if closeErr := pool.chain.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// Dialer returns a DialFunc that picks a proxy for every new connection
+func (pool *ProxyPool) Dialer(timeout time.Duration) fasthttp.DialFunc {
+
+	return FastHTTPDialer(context.Background(), timeout, pool.DialContext)
+}
+
+// DialContext connects to addr through the proxy picked by the selector
+func (pool *ProxyPool) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	conn, _, err := pool.dialContext(ctx, network, addr)
+	return conn, err
+}
+
+// dialContext also reports the proxy that was picked
+func (pool *ProxyPool) dialContext(ctx context.Context, network, addr string) (net.Conn, *PoolProxy, error) {
+
+	if err := checkNetwork(network); err != nil {
+		return nil, nil, err
+	}
+
+	if len(pool.proxies) == 0 {
+		return nil, nil, fmt.Errorf("proxy pool is empty")
+	}
+
+	p := pool.selector.Select(addr, pool.proxies)
+
+	var dial DialContextFunc
+	if pool.chain != nil {
+		dial = p.Proxy.DialerThrough(pool.chain.DialContext)
+	} else {
+		dial = p.Proxy.dialer(directDialContext)
+	}
+
+	if dial == nil {
+		return nil, p, fmt.Errorf("unsupported proxy: %s", p.Proxy.String())
+	}
+
+	start := time.Now()
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		//a cancelled dial says nothing about the proxy
+		if ctx.Err() == context.Canceled {
+			return nil, p, err
+		}
+		if selector, ok := pool.selector.(FailureSelector); ok {
+			selector.Failed(addr, p)
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			p.observe(deadline.Sub(start))
+		} else {
+			p.observe(time.Since(start))
+		}
+		return nil, p, err
+	}
+
+	p.observe(time.Since(start))
+	return conn, p, nil
+}
+
+// Latency is the smoothed dial time through this proxy, 0 when unknown
+func (p *PoolProxy) Latency() time.Duration {
+
+	return time.Duration(atomic.LoadInt64(&p.latency))
+}
+
+func (p *PoolProxy) SetLatency(latency time.Duration) {
+
+	atomic.StoreInt64(&p.latency, int64(latency))
+}
+
+func (p *PoolProxy) observe(latency time.Duration) {
+
+	if latency <= 0 {
+		latency = time.Second * 5
+	}
+
+	for {
+		old := atomic.LoadInt64(&p.latency)
+		value := int64(latency)
+		if old > 0 {
+			//exponentially weighted moving average
+			value = (old*7 + int64(latency)) / 8
+		}
+		if atomic.CompareAndSwapInt64(&p.latency, old, value) {
+			return
+		}
+	}
+}
+
+type RoundRobinSelector struct {
+	counter uint64
+}
+
+func (s *RoundRobinSelector) Select(addr string, proxies []*PoolProxy) *PoolProxy {
+
+	return proxies[(atomic.AddUint64(&s.counter, 1)-1)%uint64(len(proxies))]
+}
+
+type RandomSelector struct {
+}
+
+func (s *RandomSelector) Select(addr string, proxies []*PoolProxy) *PoolProxy {
+
+	return proxies[rand.Intn(len(proxies))]
+}
+
+// LatencySelector picks proxies at random, weighted by the inverse of
+// their latency. Proxies without a measurement get the average weight.
+type LatencySelector struct {
+}
+
+func (s *LatencySelector) Select(addr string, proxies []*PoolProxy) *PoolProxy {
+
+	var (
+		weights = make([]float64, len(proxies))
+		total   float64
+		known   int
+	)
+
+	for i, p := range proxies {
+		if latency := p.Latency(); latency > 0 {
+			weights[i] = 1 / latency.Seconds()
+			total += weights[i]
+			known++
+		}
+	}
+
+	average := 1.0
+	if known > 0 {
+		average = total / float64(known)
+	}
+
+	for i := range weights {
+		if weights[i] == 0 {
+			weights[i] = average
+			total += average
+		}
+	}
+
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		if r -= weight; r < 0 {
+			return proxies[i]
+		}
+	}
+
+	return proxies[len(proxies)-1]
+}
+
+// stickyTTL is how long a host keeps its proxy without being dialed
+const stickyTTL = time.Minute * 10
+
+// StickySelector keeps every target host on the same proxy. A host moves to
+// another proxy when a dial through its current one fails, hosts idle for
+// stickyTTL are forgotten.
+type StickySelector struct {
+	mu      sync.Mutex
+	counter int
+	hosts   map[string]*stickyEntry
+	failed  map[string]*stickyEntry
+	swept   time.Time
+}
+
+type stickyEntry struct {
+	proxy *PoolProxy
+	used  time.Time
+}
+
+func (s *StickySelector) Select(addr string, proxies []*PoolProxy) *PoolProxy {
+
+	host := stickyHost(addr)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hosts == nil {
+		s.hosts = map[string]*stickyEntry{}
+	}
+
+	if now.Sub(s.swept) > stickyTTL {
+		s.sweep(now)
+	}
+
+	if entry, exist := s.hosts[host]; exist {
+		entry.used = now
+		return entry.proxy
+	}
+
+	p := proxies[s.counter%len(proxies)]
+	s.counter++
+
+	//the counter may point at the proxy that just failed for this host
+	if failed, exist := s.failed[host]; exist {
+		if p == failed.proxy && len(proxies) > 1 {
+			p = proxies[s.counter%len(proxies)]
+			s.counter++
+		}
+		delete(s.failed, host)
+	}
+
+	s.hosts[host] = &stickyEntry{proxy: p, used: now}
+
+	return p
+}
+
+func (s *StickySelector) Failed(addr string, p *PoolProxy) {
+
+	host := stickyHost(addr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exist := s.hosts[host]; exist && entry.proxy == p {
+		delete(s.hosts, host)
+
+		if s.failed == nil {
+			s.failed = map[string]*stickyEntry{}
+		}
+		s.failed[host] = &stickyEntry{proxy: p, used: time.Now()}
+	}
+}
+
+// sweep drops the hosts not dialed for stickyTTL
+func (s *StickySelector) sweep(now time.Time) {
+
+	for host, entry := range s.hosts {
+		if now.Sub(entry.used) > stickyTTL {
+			delete(s.hosts, host)
+		}
+	}
+
+	for host, entry := range s.failed {
+		if now.Sub(entry.used) > stickyTTL {
+			delete(s.failed, host)
+		}
+	}
+
+	s.swept = now
+}
+
+// stickyHost drops the port of addr, http and https to a host share a proxy
+func stickyHost(addr string) string {
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
@@ -0,0 +1,506 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyServer accepts SOCKS5 and HTTP proxy clients on one port and forwards
+// every connection through the upstream, a single proxy, a chain or a pool
+type ProxyServer struct {
+	timeout time.Duration
+	user    string
+	pass    string
+	dial    func(addr string) (net.Conn, string, error)
+	logger  func(client, target, upstream string, err error)
+}
+
+// relayIdleTimeout ends a relay whose one direction finished on a connection
+// that cannot half-close, once the other stays silent that long
+const relayIdleTimeout = time.Second * 30
+
+var errNoHalfClose = errors.New("connection cannot half-close")
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func NewProxyServer() *ProxyServer {
+
+	server := &ProxyServer{
+		timeout: time.Second * 5,
+		logger:  func(client, target, upstream string, err error) {},
+	}
+
+	return server.SetDirect()
+}
+
+func (s *ProxyServer) SetTimeout(timeout time.Duration) *ProxyServer {
+
+	s.timeout = timeout
+	return s
+}
+
+// SetAuth makes clients log in, SOCKS5 clients with the username/password
+// method and HTTP clients with Basic. An empty user serves without login.
+func (s *ProxyServer) SetAuth(user, pass string) *ProxyServer {
+
+	s.user = user
+	s.pass = pass
+	return s
+}
+
+// SetLogger receives every relayed connection, nothing is logged by default
+func (s *ProxyServer) SetLogger(logger func(client, target, upstream string, err error)) *ProxyServer {
+
+	s.logger = logger
+	return s
+}
+
+func (s *ProxyServer) SetDirect() *ProxyServer {
+
+	s.dial = func(addr string) (net.Conn, string, error) {
+		conn, err := FastHTTPDialer(context.Background(), s.timeout, directDialContext)(addr)
+		return conn, "direct", err
+	}
+	return s
+}
+
+func (s *ProxyServer) SetProxy(proxy *Proxy) *ProxyServer {
+
+	dial := proxy.Dialer(s.timeout)
+
+	s.dial = func(addr string) (net.Conn, string, error) {
+		conn, err := dial(addr)
+		return conn, proxy.String(), err
+	}
+	return s
+}
+
+func (s *ProxyServer) SetProxyChain(chain *ProxyChain) *ProxyServer {
+
+	dial := chain.Dialer(s.timeout)
+
+	s.dial = func(addr string) (net.Conn, string, error) {
+		conn, err := dial(addr)
+		return conn, chain.String(), err
+	}
+	return s
+}
+
+func (s *ProxyServer) SetProxyPool(pool *ProxyPool) *ProxyServer {
+
+	s.dial = func(addr string) (net.Conn, string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+
+		conn, p, err := pool.dialContext(ctx, "tcp", addr)
+		if p == nil {
+			return conn, "pool", err
+		}
+		return conn, p.Proxy.String(), err
+	}
+	return s
+}
+
+func (s *ProxyServer) ListenAndServe(addr string, ready func(addr string)) error {
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if ready != nil {
+		ready(listener.Addr().String())
+	}
+
+	return s.Serve(listener)
+}
+
+func (s *ProxyServer) Serve(listener net.Listener) error {
+
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *ProxyServer) handle(conn net.Conn) {
+
+	defer conn.Close()
+
+	//the client has to speak first
+	_ = conn.SetReadDeadline(time.Now().Add(s.timeout))
+
+	reader := bufio.NewReader(conn)
+	version, err := reader.Peek(1)
+	if err != nil {
+		return
+	}
+
+	client := &bufferedConn{Conn: conn, reader: reader}
+
+	if version[0] == 0x05 {
+		s.handleSocks5(client)
+	} else {
+		s.handleHTTP(client)
+	}
+}
+
+func (s *ProxyServer) handleSocks5(client *bufferedConn) {
+
+	//greeting: version, method count, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(client, header); err != nil {
+		return
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(client, methods); err != nil {
+		return
+	}
+
+	//no authentication, or username/password when a login is required
+	method := byte(0x00)
+	if s.user != "" {
+		method = 0x02
+	}
+
+	if !bytes.Contains(methods, []byte{method}) {
+		_, _ = client.Write([]byte{0x05, 0xFF})
+		return
+	}
+
+	if _, err := client.Write([]byte{0x05, method}); err != nil {
+		return
+	}
+
+	if s.user != "" && !s.socks5Login(client) {
+		return
+	}
+
+	//request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(client, request); err != nil {
+		return
+	}
+
+	var host string
+
+	switch request[3] {
+	case 0x01:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(client, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 0x04:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(client, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(client, length); err != nil {
+			return
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(client, domain); err != nil {
+			return
+		}
+		host = string(domain)
+	default:
+		_, _ = client.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(client, port); err != nil {
+		return
+	}
+
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	//only connect is supported
+	if request[1] != 0x01 {
+		_, _ = client.Write([]byte{0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	upstream, name, err := s.dial(target)
+	s.logger(client.RemoteAddr().String(), target, name, err)
+
+	if err != nil {
+		_, _ = client.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	if _, err = client.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		upstream.Close()
+		return
+	}
+
+	relay(client, upstream)
+}
+
+// socks5Login runs the username/password sub-negotiation of RFC 1929
+func (s *ProxyServer) socks5Login(client *bufferedConn) bool {
+
+	//version, username length
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(client, header); err != nil {
+		return false
+	}
+
+	user := make([]byte, header[1])
+	if _, err := io.ReadFull(client, user); err != nil {
+		return false
+	}
+
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(client, length); err != nil {
+		return false
+	}
+
+	pass := make([]byte, length[0])
+	if _, err := io.ReadFull(client, pass); err != nil {
+		return false
+	}
+
+	if header[0] != 0x01 || !s.login(string(user), string(pass)) {
+		_, _ = client.Write([]byte{0x01, 0x01})
+		return false
+	}
+
+	_, err := client.Write([]byte{0x01, 0x00})
+	return err == nil
+}
+
+func (s *ProxyServer) login(user, pass string) bool {
+
+	//evaluate both to not leak which one was wrong
+	validUser := subtle.ConstantTimeCompare([]byte(user), []byte(s.user))
+	validPass := subtle.ConstantTimeCompare([]byte(pass), []byte(s.pass))
+
+	return validUser&validPass == 1
+}
+
+func (s *ProxyServer) handleHTTP(client *bufferedConn) {
+
+	request, err := nethttp.ReadRequest(client.reader)
+	if err != nil {
+		return
+	}
+
+	if s.user != "" {
+		user, pass, _ := parseBasicAuth(request.Header.Get("Proxy-Authorization"))
+		if !s.login(user, pass) {
+			_, _ = client.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"r4scan\"\r\nConnection: close\r\n\r\n"))
+			return
+		}
+	}
+
+	target := request.Host
+	if request.Method != nethttp.MethodConnect {
+		if request.URL.Host == "" {
+			_, _ = client.Write([]byte("HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n"))
+			return
+		}
+		target = request.URL.Host
+	}
+
+	if _, _, err = net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(strings.Trim(target, "[]"), "80")
+	}
+
+	upstream, name, err := s.dial(target)
+	s.logger(client.RemoteAddr().String(), target, name, err)
+
+	if err != nil {
+		_, _ = client.Write([]byte("HTTP/1.1 502 Bad Gateway\r\nConnection: close\r\n\r\n"))
+		return
+	}
+
+	if request.Method != nethttp.MethodConnect {
+		s.forwardHTTP(client, upstream, request)
+		return
+	}
+
+	if _, err = client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		upstream.Close()
+		return
+	}
+
+	relay(client, upstream)
+}
+
+// forwardHTTP sends a single plain http request and its response, both sides
+// are told to close so a keep-alive request never reaches the target raw
+func (s *ProxyServer) forwardHTTP(client *bufferedConn, upstream net.Conn, request *nethttp.Request) {
+
+	defer upstream.Close()
+
+	request.Header.Del("Proxy-Connection")
+	request.Header.Del("Proxy-Authorization")
+	request.Close = true
+
+	if err := request.Write(upstream); err != nil {
+		return
+	}
+
+	response, err := nethttp.ReadResponse(bufio.NewReader(upstream), request)
+	if err != nil {
+		_, _ = client.Write([]byte("HTTP/1.1 502 Bad Gateway\r\nConnection: close\r\n\r\n"))
+		return
+	}
+
+	defer response.Body.Close()
+
+	response.Close = true
+	_ = response.Write(client)
+}
+
+func parseBasicAuth(header string) (user, pass string, ok bool) {
+
+	scheme, value, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "basic") {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return "", "", false
+	}
+
+	return strings.Cut(string(decoded), ":")
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+
+	return c.reader.Read(b)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+
+	if conn, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite()
+	}
+
+	return errNoHalfClose
+}
+
+// closeWrite half-closes conn and reports whether it could
+func closeWrite(conn net.Conn) bool {
+
+	if conn, ok := conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite() == nil
+	}
+
+	return false
+}
+
+// relayWatchdog closes both sides of a relay once it stays silent for
+// relayIdleTimeout, it only runs after one direction has finished
+type relayWatchdog struct {
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func (w *relayWatchdog) start(stop func()) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(relayIdleTimeout, stop)
+	}
+}
+
+func (w *relayWatchdog) touch() {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Reset(relayIdleTimeout)
+	}
+}
+
+func (w *relayWatchdog) stop() {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// watchedReader touches the watchdog on every read that moves data
+type watchedReader struct {
+	conn     net.Conn
+	watchdog *relayWatchdog
+}
+
+func (r *watchedReader) Read(b []byte) (int, error) {
+
+	n, err := r.conn.Read(b)
+	if n > 0 {
+		r.watchdog.touch()
+	}
+
+	return n, err
+}
+
+// relay copies both directions until they are finished. A direction ending
+// early half-closes its destination so the other one can still answer. When
+// the destination cannot half-close, like ss, v2ray or smux streams, the
+// other direction goes on until it is silent for relayIdleTimeout.
+func relay(client net.Conn, upstream net.Conn) {
+
+	var (
+		wg       sync.WaitGroup
+		watchdog relayWatchdog
+	)
+
+	_ = client.SetDeadline(time.Time{})
+
+	pipe := func(dst net.Conn, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, &watchedReader{conn: src, watchdog: &watchdog})
+		if !closeWrite(dst) {
+			watchdog.start(func() {
+				client.Close()
+				upstream.Close()
+			})
+		}
+	}
+
+	wg.Add(2)
+	go pipe(upstream, client)
+	go pipe(client, upstream)
+	wg.Wait()
+	watchdog.stop()
+
+	client.Close()
+	upstream.Close()
+}
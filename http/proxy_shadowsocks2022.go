@@ -0,0 +1,293 @@
+package http
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+	"lukechampine.com/blake3"
+	mathRand "math/rand"
+	"net"
+	"r4scan/util"
+	"strings"
+	"time"
+)
+
+// ShadowSocks2022KeySize is the psk length of the SIP022 methods, salts and
+// session keys have the same length
+var ShadowSocks2022KeySize = map[string]int{
+	"2022-blake3-aes-128-gcm":       16,
+	"2022-blake3-aes-256-gcm":       32,
+	"2022-blake3-chacha20-poly1305": 32,
+}
+
+const (
+	shadowSocks2022MaxPayload = 0xffff
+	shadowSocks2022MaxPadding = 900
+	shadowSocks2022TimeWindow = 30 * time.Second
+)
+
+// newShadowSocks2022Keys decodes the base64 psks of a 2022 password. Multi-user
+// servers take "iPSK:...:uPSK", the identity keys come before the user key.
+func newShadowSocks2022Keys(method, password string) ([][]byte, error) {
+
+	var keys [][]byte
+
+	for _, psk := range strings.Split(password, ":") {
+		key, err := util.Base64URLDecode(psk)
+		if err != nil || len(key) != ShadowSocks2022KeySize[method] {
+			return nil, fmt.Errorf("invalid %s psk \"%s\"", method, psk)
+		}
+		keys = append(keys, []byte(key))
+	}
+
+	if len(keys) > 1 && !strings.Contains(method, "-aes-") {
+		return nil, fmt.Errorf("%s does not support identity headers", method)
+	}
+
+	return keys, nil
+}
+
+// shadowSocks2022Conn is the client side of a SIP022 tcp stream. The request
+// header goes out with writeHeader before any Write.
+type shadowSocks2022Conn struct {
+	net.Conn
+	method     string
+	keys       [][]byte
+	salt       []byte
+	writer     cipher.AEAD
+	writeNonce []byte
+	writeBuf   []byte
+	reader     cipher.AEAD
+	readNonce  []byte
+	readBuf    []byte
+	pending    []byte
+}
+
+func newShadowSocks2022Conn(conn net.Conn, method string, keys [][]byte) *shadowSocks2022Conn {
+
+	return &shadowSocks2022Conn{
+		Conn:   conn,
+		method: method,
+		keys:   keys,
+	}
+}
+
+func (c *shadowSocks2022Conn) userKey() []byte {
+
+	return c.keys[len(c.keys)-1]
+}
+
+func (c *shadowSocks2022Conn) newAEAD(salt []byte) (cipher.AEAD, error) {
+
+	key := make([]byte, len(salt))
+	blake3.DeriveKey(key, "shadowsocks 2022 session subkey", append(append([]byte{}, c.userKey()...), salt...))
+
+	if strings.HasSuffix(c.method, "chacha20-poly1305") {
+		return chacha20poly1305.New(key)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// writeHeader sends the salt, the identity headers and the request header
+// carrying the target address. The header is padded since no payload goes
+// along with it. A random salt is drawn unless one is already set.
+func (c *shadowSocks2022Conn) writeHeader(addr []byte) (err error) {
+
+	if c.salt == nil {
+		c.salt = make([]byte, len(c.userKey()))
+		if _, err = rand.Read(c.salt); err != nil {
+			return err
+		}
+	}
+
+	buf := append([]byte{}, c.salt...)
+
+	for i := 0; i < len(c.keys)-1; i++ {
+		identityKey := make([]byte, len(c.salt))
+		blake3.DeriveKey(identityKey, "shadowsocks 2022 identity subkey", append(append([]byte{}, c.keys[i]...), c.salt...))
+
+		block, err := aes.NewCipher(identityKey)
+		if err != nil {
+			return err
+		}
+
+		hash := blake3.Sum256(c.keys[i+1])
+		identity := make([]byte, aes.BlockSize)
+		block.Encrypt(identity, hash[:aes.BlockSize])
+		buf = append(buf, identity...)
+	}
+
+	if c.writer, err = c.newAEAD(c.salt); err != nil {
+		return err
+	}
+	c.writeNonce = make([]byte, c.writer.NonceSize())
+
+	padding := 1 + mathRand.Intn(shadowSocks2022MaxPadding)
+
+	variable := make([]byte, len(addr)+2+padding)
+	copy(variable, addr)
+	binary.BigEndian.PutUint16(variable[len(addr):], uint16(padding))
+
+	//client stream type, timestamp and length of the variable header
+	fixed := make([]byte, 1+8+2)
+	binary.BigEndian.PutUint64(fixed[1:], uint64(time.Now().Unix()))
+	binary.BigEndian.PutUint16(fixed[9:], uint16(len(variable)))
+
+	buf = c.seal(buf, fixed)
+	buf = c.seal(buf, variable)
+
+	_, err = c.Conn.Write(buf)
+	return err
+}
+
+func (c *shadowSocks2022Conn) Write(b []byte) (n int, err error) {
+
+	if c.writer == nil {
+		return 0, fmt.Errorf("ss: request header not sent")
+	}
+
+	for len(b) > 0 {
+		size := len(b)
+		if size > shadowSocks2022MaxPayload {
+			size = shadowSocks2022MaxPayload
+		}
+
+		length := make([]byte, 2)
+		binary.BigEndian.PutUint16(length, uint16(size))
+
+		c.writeBuf = c.seal(c.writeBuf[:0], length)
+		c.writeBuf = c.seal(c.writeBuf, b[:size])
+
+		if _, err = c.Conn.Write(c.writeBuf); err != nil {
+			return n, err
+		}
+
+		n += size
+		b = b[size:]
+	}
+
+	return n, nil
+}
+
+func (c *shadowSocks2022Conn) Read(b []byte) (int, error) {
+
+	for len(c.pending) == 0 {
+		if err := c.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+
+	return n, nil
+}
+
+// readChunk reads the next payload chunk into pending, the first one follows
+// the response header
+func (c *shadowSocks2022Conn) readChunk() (err error) {
+
+	var size int
+
+	if c.reader == nil {
+		if size, err = c.readHeader(); err != nil {
+			return err
+		}
+	} else {
+		length, err := c.open(2)
+		if err != nil {
+			return err
+		}
+		size = int(binary.BigEndian.Uint16(length))
+	}
+
+	c.pending, err = c.open(size)
+	return err
+}
+
+// readHeader checks the response header and returns the length of the first
+// payload chunk
+func (c *shadowSocks2022Conn) readHeader() (int, error) {
+
+	salt := make([]byte, len(c.salt))
+	if _, err := io.ReadFull(c.Conn, salt); err != nil {
+		return 0, err
+	}
+
+	reader, err := c.newAEAD(salt)
+	if err != nil {
+		return 0, err
+	}
+	c.reader = reader
+	c.readNonce = make([]byte, reader.NonceSize())
+	c.readBuf = make([]byte, shadowSocks2022MaxPayload+reader.Overhead())
+
+	//type, timestamp, request salt and length of the first chunk
+	header, err := c.open(1 + 8 + len(c.salt) + 2)
+	if err != nil {
+		return 0, err
+	}
+
+	if header[0] != 1 {
+		return 0, fmt.Errorf("ss: invalid response header type %d", header[0])
+	}
+
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(header[1:])), 0)
+	if diff := time.Since(timestamp); diff > shadowSocks2022TimeWindow || diff < -shadowSocks2022TimeWindow {
+		return 0, fmt.Errorf("ss: response timestamp out of window")
+	}
+
+	if !bytes.Equal(header[9:9+len(c.salt)], c.salt) {
+		return 0, fmt.Errorf("ss: response salt mismatch")
+	}
+
+	return int(binary.BigEndian.Uint16(header[9+len(c.salt):])), nil
+}
+
+func (c *shadowSocks2022Conn) seal(dst, plaintext []byte) []byte {
+
+	dst = c.writer.Seal(dst, c.writeNonce, plaintext, nil)
+	shadowSocks2022Increment(c.writeNonce)
+
+	return dst
+}
+
+// open reads and decrypts a chunk of size bytes, the result is valid until the
+// next call
+func (c *shadowSocks2022Conn) open(size int) ([]byte, error) {
+
+	buf := c.readBuf[:size+c.reader.Overhead()]
+	if _, err := io.ReadFull(c.Conn, buf); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := c.reader.Open(buf[:0], c.readNonce, buf, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ss: %v", err)
+	}
+	shadowSocks2022Increment(c.readNonce)
+
+	return plaintext, nil
+}
+
+// shadowSocks2022Increment adds one to a little endian nonce
+func shadowSocks2022Increment(nonce []byte) {
+
+	for i := range nonce {
+		nonce[i]++
+		if nonce[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+	"lukechampine.com/blake3"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sequence(start, n int) []byte {
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(start + i)
+	}
+	return b
+}
+
+func decodeHex(t *testing.T, value string) []byte {
+
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// shadowSocks2022Peer is the server side of a test stream, it opens and seals
+// chunks with a counter nonce like the client
+type shadowSocks2022Peer struct {
+	conn  net.Conn
+	aead  cipher.AEAD
+	nonce []byte
+}
+
+func newShadowSocks2022Peer(t *testing.T, conn net.Conn, method string, key []byte) *shadowSocks2022Peer {
+
+	var (
+		aead cipher.AEAD
+		err  error
+	)
+
+	if strings.HasSuffix(method, "chacha20-poly1305") {
+		aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			aead, err = cipher.NewGCM(block)
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &shadowSocks2022Peer{conn: conn, aead: aead, nonce: make([]byte, aead.NonceSize())}
+}
+
+func (p *shadowSocks2022Peer) open(t *testing.T, size int) []byte {
+
+	buf := make([]byte, size+p.aead.Overhead())
+	if _, err := io.ReadFull(p.conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := p.aead.Open(nil, p.nonce, buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowSocks2022Increment(p.nonce)
+
+	return plaintext
+}
+
+func (p *shadowSocks2022Peer) seal(dst, plaintext []byte) []byte {
+
+	dst = p.aead.Seal(dst, p.nonce, plaintext, nil)
+	shadowSocks2022Increment(p.nonce)
+
+	return dst
+}
+
+func TestShadowSocks2022Loopback(t *testing.T) {
+
+	var (
+		psk16   = "AAECAwQFBgcICQoLDA0ODw=="
+		upsk16  = "QEFCQ0RFRkdISUpLTE1OTw=="
+		psk32   = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+		target  = []byte{0x03, 11, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', 0x01, 0xbb}
+		request = []byte("GET / HTTP/1.1\r\n\r\n")
+		reply   = []byte("HTTP/1.1 204 No Content\r\n\r\n")
+	)
+
+	//session keys derived from the user psk and the salt, the identity header
+	//encrypts the hash of the user psk with the identity psk
+	cases := []struct {
+		method     string
+		password   string
+		salt       []byte
+		sessionKey string
+		identity   string
+	}{
+		{"2022-blake3-aes-128-gcm", upsk16, sequence(0x80, 16), "33f18662b66acacded1c24790894ade2", ""},
+		{"2022-blake3-aes-128-gcm", psk16 + ":" + upsk16, sequence(0x80, 16), "33f18662b66acacded1c24790894ade2", "acf952093ca5db6e11ce8310822221c6"},
+		{"2022-blake3-aes-256-gcm", psk32, sequence(0x80, 32), "11289b9d205255930f83932405c2b0a38ec32be703fe33f290ff25ffeff402f9", ""},
+		{"2022-blake3-chacha20-poly1305", psk32, sequence(0x80, 32), "11289b9d205255930f83932405c2b0a38ec32be703fe33f290ff25ffeff402f9", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+"/"+c.password, func(t *testing.T) {
+
+			keys, err := newShadowSocks2022Keys(c.method, c.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+
+			conn := newShadowSocks2022Conn(client, c.method, keys)
+			conn.salt = c.salt
+
+			go func() {
+				if err := conn.writeHeader(target); err == nil {
+					_, _ = conn.Write(request)
+				}
+			}()
+
+			salt := make([]byte, len(c.salt))
+			if _, err = io.ReadFull(server, salt); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(salt, c.salt) {
+				t.Fatalf("salt %x, want %x", salt, c.salt)
+			}
+
+			if c.identity != "" {
+				identity := make([]byte, aes.BlockSize)
+				if _, err = io.ReadFull(server, identity); err != nil {
+					t.Fatal(err)
+				}
+				if want := decodeHex(t, c.identity); !bytes.Equal(identity, want) {
+					t.Fatalf("identity header %x, want %x", identity, want)
+				}
+			}
+
+			peer := newShadowSocks2022Peer(t, server, c.method, decodeHex(t, c.sessionKey))
+
+			fixed := peer.open(t, 1+8+2)
+			if fixed[0] != 0 {
+				t.Fatalf("request header type %d", fixed[0])
+			}
+			if diff := time.Since(time.Unix(int64(binary.BigEndian.Uint64(fixed[1:])), 0)); diff > time.Minute || diff < -time.Minute {
+				t.Fatalf("request timestamp off by %s", diff)
+			}
+
+			variable := peer.open(t, int(binary.BigEndian.Uint16(fixed[9:])))
+			if !bytes.HasPrefix(variable, target) {
+				t.Fatalf("request address %x, want %x", variable[:len(target)], target)
+			}
+			if padding := int(binary.BigEndian.Uint16(variable[len(target):])); padding != len(variable)-len(target)-2 || padding == 0 {
+				t.Fatalf("invalid padding length %d", padding)
+			}
+
+			length := peer.open(t, 2)
+			if payload := peer.open(t, int(binary.BigEndian.Uint16(length))); !bytes.Equal(payload, request) {
+				t.Fatalf("payload %q, want %q", payload, request)
+			}
+
+			//the response is sealed with a key of its own salt
+			responseSalt := sequence(0xc0, len(c.salt))
+			responseKey := make([]byte, len(c.salt))
+			blake3.DeriveKey(responseKey, "shadowsocks 2022 session subkey", append(append([]byte{}, keys[len(keys)-1]...), responseSalt...))
+
+			response := newShadowSocks2022Peer(t, server, c.method, responseKey)
+
+			header := make([]byte, 1+8+len(c.salt)+2)
+			header[0] = 1
+			binary.BigEndian.PutUint64(header[1:], uint64(time.Now().Unix()))
+			copy(header[9:], c.salt)
+			binary.BigEndian.PutUint16(header[9+len(c.salt):], uint16(len(reply)))
+
+			buf := append([]byte{}, responseSalt...)
+			buf = response.seal(buf, header)
+			buf = response.seal(buf, reply)
+
+			go func() {
+				_, _ = server.Write(buf)
+			}()
+
+			got := make([]byte, len(reply))
+			if _, err = io.ReadFull(conn, got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, reply) {
+				t.Fatalf("reply %q, want %q", got, reply)
+			}
+		})
+	}
+}
+
+func TestShadowSocks2022Keys(t *testing.T) {
+
+	cases := []struct {
+		method   string
+		password string
+		keys     int
+		valid    bool
+	}{
+		{"2022-blake3-aes-128-gcm", "AAECAwQFBgcICQoLDA0ODw==", 1, true},
+		{"2022-blake3-aes-128-gcm", "AAECAwQFBgcICQoLDA0ODw==:QEFCQ0RFRkdISUpLTE1OTw==", 2, true},
+		{"2022-blake3-aes-256-gcm", "AAECAwQFBgcICQoLDA0ODw==", 0, false},
+		{"2022-blake3-chacha20-poly1305", "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=", 0, false},
+		{"2022-blake3-aes-128-gcm", "not base64", 0, false},
+	}
+
+	for _, c := range cases {
+		keys, err := newShadowSocks2022Keys(c.method, c.password)
+		if (err == nil) != c.valid || len(keys) != c.keys {
+			t.Errorf("%s %q: %d keys, error %v", c.method, c.password, len(keys), err)
+		}
+	}
+}
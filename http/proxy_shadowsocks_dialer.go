@@ -1,8 +1,10 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"github.com/Dreamacro/clash/transport/simple-obfs"
+	v2rayObfs "github.com/Dreamacro/clash/transport/v2ray-plugin"
 	shadowsocks2 "github.com/shadowsocks/go-shadowsocks2/core"
 	"github.com/shadowsocks/shadowsocks-go/shadowsocks"
 	"github.com/valyala/fasthttp"
@@ -16,35 +18,40 @@ import (
 )
 
 type ShadowSocks struct {
-	Server   string
-	Port     int
-	Cipher   string
-	Password string
-	Key      []byte
-	Obfs     *ShadowSocksObfs
+	Server      string
+	Port        int
+	Cipher      string
+	Password    string
+	Key         []byte
+	Keys        [][]byte
+	Obfs        *ShadowSocksObfs
+	V2rayPlugin *ShadowSocksV2rayPlugin
 }
 
 var ShadowSocksCipherList = map[string]struct{}{
-	"aead_aes_128_gcm":       {},
-	"aead_aes_256_gcm":       {},
-	"aead_chacha20_poly1305": {},
-	"aes-128-gcm":            {},
-	"aes-256-gcm":            {},
-	"aes-128-cfb":            {},
-	"aes-192-cfb":            {},
-	"aes-256-cfb":            {},
-	"aes-128-ctr":            {},
-	"aes-192-ctr":            {},
-	"aes-256-ctr":            {},
-	"des-cfb":                {},
-	"bf-cfb":                 {},
-	"cast5-cfb":              {},
-	"rc4-md5":                {},
-	"rc4-md5-6":              {},
-	"chacha20":               {},
-	"chacha20-ietf":          {},
-	"chacha20-ietf-poly1305": {},
-	"salsa20":                {},
+	"aead_aes_128_gcm":              {},
+	"aead_aes_256_gcm":              {},
+	"aead_chacha20_poly1305":        {},
+	"aes-128-gcm":                   {},
+	"aes-256-gcm":                   {},
+	"aes-128-cfb":                   {},
+	"aes-192-cfb":                   {},
+	"aes-256-cfb":                   {},
+	"aes-128-ctr":                   {},
+	"aes-192-ctr":                   {},
+	"aes-256-ctr":                   {},
+	"des-cfb":                       {},
+	"bf-cfb":                        {},
+	"cast5-cfb":                     {},
+	"rc4-md5":                       {},
+	"rc4-md5-6":                     {},
+	"chacha20":                      {},
+	"chacha20-ietf":                 {},
+	"chacha20-ietf-poly1305":        {},
+	"salsa20":                       {},
+	"2022-blake3-aes-128-gcm":       {},
+	"2022-blake3-aes-256-gcm":       {},
+	"2022-blake3-chacha20-poly1305": {},
 }
 
 type ShadowSocksObfs struct {
@@ -52,9 +59,25 @@ type ShadowSocksObfs struct {
 	Host   string
 }
 
+// ShadowSocksV2rayPlugin is the websocket mode of v2ray-plugin, the quic mode
+// is not supported
+type ShadowSocksV2rayPlugin struct {
+	Host           string
+	Path           string
+	Headers        map[string]string
+	TLS            bool
+	SkipCertVerify bool
+	Mux            bool
+}
+
 func (proxy *Proxy) ShadowSocksDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	return func(addr string) (net.Conn, error) {
+	return FastHTTPDialer(context.Background(), timeout, proxy.shadowSocksDialer(directDialContext))
+}
+
+func (proxy *Proxy) shadowSocksDialer(dial DialContextFunc) DialContextFunc {
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 
 		var (
 			ssCipher  *shadowsocks.Cipher
@@ -63,26 +86,28 @@ func (proxy *Proxy) ShadowSocksDialer(timeout time.Duration) fasthttp.DialFunc {
 			err       error
 		)
 
-		ssCipher, _ = shadowsocks.NewCipher(proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Password)
-		if ssCipher == nil {
-			ss2Cipher, _ = shadowsocks2.PickCipher(proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Key, proxy.ShadowSocks.Password)
+		ss2022 := len(proxy.ShadowSocks.Keys) > 0
+
+		if !ss2022 {
+			ssCipher, _ = shadowsocks.NewCipher(proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Password)
+			if ssCipher == nil {
+				ss2Cipher, _ = shadowsocks2.PickCipher(proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Key, proxy.ShadowSocks.Password)
+			}
 		}
 
-		if ssCipher == nil && ss2Cipher == nil {
+		if !ss2022 && ssCipher == nil && ss2Cipher == nil {
 			return nil, fmt.Errorf("ss: invalid cipher \"%s\"", proxy.ShadowSocks.Cipher)
 		}
 
 		proxyAddr := net.JoinHostPort(proxy.ShadowSocks.Server, strconv.Itoa(proxy.ShadowSocks.Port))
-		if timeout == 0 {
-			conn, err = fasthttp.DialDualStack(proxyAddr)
-		} else {
-			conn, err = fasthttp.DialDualStackTimeout(proxyAddr, timeout)
-		}
+		conn, err = dial(ctx, network, proxyAddr)
 
 		if err != nil {
 			return nil, err
 		}
 
+		raw := conn
+
 		if proxy.ShadowSocks.Obfs != nil {
 			if proxy.ShadowSocks.Obfs.Schema == "http" {
 				conn = obfs.NewHTTPObfs(conn, proxy.ShadowSocks.Obfs.Host, strconv.Itoa(proxy.ShadowSocks.Port))
@@ -91,18 +116,45 @@ func (proxy *Proxy) ShadowSocksDialer(timeout time.Duration) fasthttp.DialFunc {
 			}
 		}
 
-		if ssCipher != nil {
+		if plugin := proxy.ShadowSocks.V2rayPlugin; plugin != nil {
+			if err = handshake(ctx, raw, func() error {
+				conn, err = v2rayObfs.NewV2rayObfs(conn, &v2rayObfs.Option{
+					Host:           plugin.Host,
+					Port:           strconv.Itoa(proxy.ShadowSocks.Port),
+					Path:           plugin.Path,
+					Headers:        plugin.Headers,
+					TLS:            plugin.TLS,
+					SkipCertVerify: plugin.SkipCertVerify,
+					Mux:            plugin.Mux,
+				})
+				return err
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case ss2022:
+			conn = newShadowSocks2022Conn(conn, proxy.ShadowSocks.Cipher, proxy.ShadowSocks.Keys)
+		case ssCipher != nil:
 			conn = shadowsocks.NewConn(conn, ssCipher.Copy())
-		} else {
+		default:
 			conn = ss2Cipher.StreamConn(conn)
 		}
 
 		rawAddr, err := shadowsocks.RawAddr(addr)
 		if err != nil {
+			raw.Close()
 			return nil, err
 		}
 
-		if _, err = conn.Write(rawAddr); err != nil {
+		if err = handshake(ctx, raw, func() error {
+			if ss2022Conn, ok := conn.(*shadowSocks2022Conn); ok {
+				return ss2022Conn.writeHeader(rawAddr)
+			}
+			_, err := conn.Write(rawAddr)
+			return err
+		}); err != nil {
 			return nil, err
 		}
 
@@ -115,12 +167,14 @@ func (proxy *Proxy) ShadowSocksDialer(timeout time.Duration) fasthttp.DialFunc {
 func newShadowSocks(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 
 	var (
-		server   string
-		port     int
-		cipher   string
-		password string
-		key      []byte
-		obfs     *ShadowSocksObfs
+		server      string
+		port        int
+		cipher      string
+		password    string
+		key         []byte
+		keys        [][]byte
+		obfs        *ShadowSocksObfs
+		v2rayPlugin *ShadowSocksV2rayPlugin
 	)
 
 	if errs := validator.Var(urls.Hostname(), "required,fqdn|ip"); errs != nil {
@@ -191,18 +245,24 @@ func newShadowSocks(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 			return nil, fmt.Errorf("parse error: invalid userInfo")
 		}
 
-		userInfo, err := util.Base64URLDecode(urls.User.String())
-		if err != nil {
-			return nil, fmt.Errorf("parse error: %v", err)
-		}
+		//the 2022 methods leave the userinfo percent encoded instead of base64
+		if value, exist := urls.User.Password(); exist {
+			cipher = strings.ToLower(urls.User.Username())
+			password = value
+		} else {
+			userInfo, err := util.Base64URLDecode(urls.User.String())
+			if err != nil {
+				return nil, fmt.Errorf("parse error: %v", err)
+			}
 
-		userSplit := strings.Index(userInfo, ":")
-		if userSplit < 0 {
-			return nil, fmt.Errorf("parse error: invalid userInfo")
-		}
+			userSplit := strings.Index(userInfo, ":")
+			if userSplit < 0 {
+				return nil, fmt.Errorf("parse error: invalid userInfo")
+			}
 
-		cipher = strings.ToLower(userInfo[:userSplit])
-		password = userInfo[userSplit+1:]
+			cipher = strings.ToLower(userInfo[:userSplit])
+			password = userInfo[userSplit+1:]
+		}
 
 		if _, exist := ShadowSocksCipherList[cipher]; !exist {
 			return nil, fmt.Errorf("parse error: invalid cipher \"%s\"", cipher)
@@ -222,21 +282,15 @@ func newShadowSocks(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 			return nil, fmt.Errorf("parse error: invalid query")
 		}
 
-		queryRaw = strings.ReplaceAll(queryRaw, ";", "&")
-		query, err := url.ParseQuery(queryRaw)
-		if err != nil {
-			return nil, fmt.Errorf("parse error: invalid params")
-		}
+		query := parsePluginOpts(queryRaw)
 
-		if query.Has("plugin") && query.Has("obfs") {
+		switch plugin := strings.ToLower(strings.TrimSpace(query.Get("plugin"))); plugin {
+		case "":
+		case "obfs-local", "simple-obfs":
 
-			plugin := strings.ToLower(query.Get("plugin"))
 			obfsSchema := strings.ToLower(query.Get("obfs"))
 			obfsHost := query.Get("obfs-host")
 
-			if plugin != "obfs-local" && plugin != "simple-obfs" {
-				return nil, fmt.Errorf("parse error: unknown plugin \"%s\"", plugin)
-			}
 			if obfsSchema != "http" && obfsSchema != "tls" {
 				return nil, fmt.Errorf("parse error: unknown obfs schema \"%s\"", obfsSchema)
 			}
@@ -246,6 +300,20 @@ func newShadowSocks(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 				Host:   obfsHost,
 			}
 
+		case "v2ray-plugin":
+
+			if v2rayPlugin, err = newShadowSocksV2rayPlugin(query, server); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("parse error: unknown plugin \"%s\"", plugin)
+		}
+	}
+
+	if _, exist := ShadowSocks2022KeySize[cipher]; exist {
+		if keys, err = newShadowSocks2022Keys(cipher, password); err != nil {
+			return nil, fmt.Errorf("parse error: %v", err)
 		}
 	}
 
@@ -255,15 +323,123 @@ func newShadowSocks(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		Schema: "SS",
 		Url:    urls,
 		ShadowSocks: ShadowSocks{
-			Server:   server,
-			Port:     port,
-			Cipher:   cipher,
-			Password: password,
-			Key:      key,
-			Obfs:     obfs,
+			Server:      server,
+			Port:        port,
+			Cipher:      cipher,
+			Password:    password,
+			Key:         key,
+			Keys:        keys,
+			Obfs:        obfs,
+			V2rayPlugin: v2rayPlugin,
 		},
 	}
 
 	return
 
 }
+
+// parsePluginOpts splits the query and the SIP003 plugin options on ; and &.
+// A backslash escapes the next character, values may contain ; and = that way.
+func parsePluginOpts(raw string) url.Values {
+
+	var (
+		query      = url.Values{}
+		key, value strings.Builder
+		inValue    bool
+	)
+
+	flush := func() {
+		if key.Len() > 0 {
+			query.Add(key.String(), value.String())
+		}
+		key.Reset()
+		value.Reset()
+		inValue = false
+	}
+
+	for i := 0; i < len(raw); i++ {
+
+		c := raw[i]
+
+		switch {
+		case c == '\\' && i+1 < len(raw):
+			i++
+			c = raw[i]
+		case c == ';' || c == '&':
+			flush()
+			continue
+		case c == '=' && !inValue:
+			inValue = true
+			continue
+		}
+
+		if inValue {
+			value.WriteByte(c)
+		} else {
+			key.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return query
+}
+
+// newShadowSocksV2rayPlugin reads the SIP003 options of v2ray-plugin. The host
+// defaults to the server and mux is on unless the link sets mux=0, the
+// skip-cert-verify flag and the headers follow the clash options.
+func newShadowSocksV2rayPlugin(query url.Values, server string) (*ShadowSocksV2rayPlugin, error) {
+
+	if mode := strings.ToLower(query.Get("mode")); mode != "" && mode != "websocket" {
+		return nil, fmt.Errorf("parse error: unsupported v2ray-plugin mode \"%s\"", mode)
+	}
+
+	plugin := &ShadowSocksV2rayPlugin{
+		Host: strings.TrimSpace(query.Get("host")),
+		Path: strings.TrimSpace(query.Get("path")),
+		TLS:  query.Has("tls"),
+		Mux:  true,
+	}
+
+	if plugin.Host == "" {
+		plugin.Host = server
+	}
+
+	if plugin.Path == "" {
+		plugin.Path = "/"
+	} else if !strings.HasPrefix(plugin.Path, "/") {
+		return nil, fmt.Errorf("parse error: invalid v2ray-plugin path \"%s\"", plugin.Path)
+	}
+
+	headers, err := parseHeaders(query["header"])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range headers {
+		if plugin.Headers == nil {
+			plugin.Headers = map[string]string{}
+		}
+		plugin.Headers[header[0]] = header[1]
+	}
+
+	if value, exist := query["skip-cert-verify"]; exist && value[0] != "" {
+		if plugin.SkipCertVerify, err = strconv.ParseBool(value[0]); err != nil {
+			return nil, fmt.Errorf("parse error: invalid v2ray-plugin skip-cert-verify \"%s\"", value[0])
+		}
+	} else {
+		plugin.SkipCertVerify = exist
+	}
+
+	//the plugin takes the number of mux connections, every ss connection
+	//here gets a tunnel of its own so only zero matters
+	if value := query.Get("mux"); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil || concurrency < 0 {
+			return nil, fmt.Errorf("parse error: invalid v2ray-plugin mux \"%s\"", value)
+		}
+		plugin.Mux = concurrency > 0
+	}
+
+	return plugin, nil
+}
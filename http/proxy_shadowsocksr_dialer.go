@@ -1,7 +1,12 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"github.com/Dreamacro/clash/transport/shadowsocks/core"
+	"github.com/Dreamacro/clash/transport/shadowsocks/shadowstream"
+	clashObfs "github.com/Dreamacro/clash/transport/ssr/obfs"
+	clashProtocol "github.com/Dreamacro/clash/transport/ssr/protocol"
 	shadowSocksR "github.com/sun8911879/shadowsocksR"
 	"github.com/sun8911879/shadowsocksR/obfs"
 	"github.com/sun8911879/shadowsocksR/protocol"
@@ -50,6 +55,7 @@ var ShadowSocksRCipherList = map[string]struct{}{
 	"idea-cfb":         {},
 	"rc2-cfb":          {},
 	"seed-cfb":         {},
+	"none":             {},
 }
 
 var ShadowSocksRProtocolList = map[string]struct{}{
@@ -58,14 +64,17 @@ var ShadowSocksRProtocolList = map[string]struct{}{
 	"auth_sha1_v4":     {},
 	"auth_aes128_md5":  {},
 	"auth_aes128_sha1": {},
+	"auth_chain_a":     {},
+	"auth_chain_b":     {},
 }
 
 var ShadowSocksRObfsList = map[string]struct{}{
-	"plain":              {},
-	"http_simple":        {},
-	"http_post":          {},
-	"random_head":        {},
-	"tls1.2_ticket_auth": {},
+	"plain":                  {},
+	"http_simple":            {},
+	"http_post":              {},
+	"random_head":            {},
+	"tls1.2_ticket_auth":     {},
+	"tls1.2_ticket_fastauth": {},
 }
 
 type ShadowSocksRObfs struct {
@@ -80,7 +89,16 @@ type ShadowSocksRProtocol struct {
 
 func (proxy *Proxy) ShadowSocksRDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	return func(addr string) (net.Conn, error) {
+	return FastHTTPDialer(context.Background(), timeout, proxy.shadowSocksRDialer(directDialContext))
+}
+
+func (proxy *Proxy) shadowSocksRDialer(dial DialContextFunc) DialContextFunc {
+
+	if proxy.ShadowSocksR.chainTransport() {
+		return proxy.shadowSocksRChainDialer(dial)
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 
 		var (
 			cipher  *shadowSocksR.StreamCipher
@@ -95,11 +113,7 @@ func (proxy *Proxy) ShadowSocksRDialer(timeout time.Duration) fasthttp.DialFunc
 		}
 
 		proxyAddr := net.JoinHostPort(proxy.ShadowSocksR.Server, strconv.Itoa(proxy.ShadowSocksR.Port))
-		if timeout == 0 {
-			conn, err = fasthttp.DialDualStack(proxyAddr)
-		} else {
-			conn, err = fasthttp.DialDualStackTimeout(proxyAddr, timeout)
-		}
+		conn, err = dial(ctx, network, proxyAddr)
 
 		if err != nil {
 			return nil, err
@@ -110,7 +124,13 @@ func (proxy *Proxy) ShadowSocksRDialer(timeout time.Duration) fasthttp.DialFunc
 			return nil, fmt.Errorf("ssr: invalid ssr connection")
 		}
 
-		ssrConn.IObfs = obfs.NewObfs(proxy.ShadowSocksR.Obfs.Schema)
+		//fastauth only differs on the server side
+		obfsSchema := proxy.ShadowSocksR.Obfs.Schema
+		if obfsSchema == "tls1.2_ticket_fastauth" {
+			obfsSchema = "tls1.2_ticket_auth"
+		}
+
+		ssrConn.IObfs = obfs.NewObfs(obfsSchema)
 		if ssrConn.IObfs == nil {
 			return nil, fmt.Errorf("ssr: cannot create obfs")
 		}
@@ -139,7 +159,10 @@ func (proxy *Proxy) ShadowSocksRDialer(timeout time.Duration) fasthttp.DialFunc
 
 		rawAddr := socks.ParseAddr(addr)
 
-		if _, err = ssrConn.Write(rawAddr); err != nil {
+		if err = handshake(ctx, conn, func() error {
+			_, err := ssrConn.Write(rawAddr)
+			return err
+		}); err != nil {
 			return nil, err
 		}
 
@@ -148,6 +171,71 @@ func (proxy *Proxy) ShadowSocksRDialer(timeout time.Duration) fasthttp.DialFunc
 
 }
 
+// shadowSocksRChainDialer dials with the ssr transport of clash, which
+// implements the auth_chain protocols and the none cipher
+func (proxy *Proxy) shadowSocksRChainDialer(dial DialContextFunc) DialContextFunc {
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+
+		ssr := proxy.ShadowSocksR
+
+		cipher, ivSize, key, err := ssr.chainCipher()
+		if err != nil {
+			return nil, fmt.Errorf("ssr: %v", err)
+		}
+
+		ssrObfs, overhead, err := clashObfs.PickObfs(ssr.Obfs.Schema, &clashObfs.Base{
+			Host:   ssr.Server,
+			Port:   ssr.Port,
+			Key:    key,
+			IVSize: ivSize,
+			Param:  ssr.Obfs.Param,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ssr: cannot create obfs")
+		}
+
+		ssrProtocol, err := clashProtocol.PickProtocol(ssr.Protocol.Schema, &clashProtocol.Base{
+			Key:      key,
+			Overhead: overhead,
+			Param:    ssr.Protocol.Param,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ssr: cannot create protocol")
+		}
+
+		proxyAddr := net.JoinHostPort(ssr.Server, strconv.Itoa(ssr.Port))
+		conn, err := dial(ctx, network, proxyAddr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tunnel := cipher.StreamConn(ssrObfs.StreamConn(conn))
+
+		//the protocol keys its authentication with the iv of the cipher
+		var iv []byte
+		if stream, ok := tunnel.(*shadowstream.Conn); ok {
+			if iv, err = stream.ObtainWriteIV(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+
+		tunnel = ssrProtocol.StreamConn(tunnel, iv)
+		rawAddr := socks.ParseAddr(addr)
+
+		if err = handshake(ctx, conn, func() error {
+			_, err := tunnel.Write(rawAddr)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		return tunnel, nil
+	}
+}
+
 func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 
 	var (
@@ -159,6 +247,7 @@ func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		password   string
 		obfsparam  string
 		protoparam string
+		remarks    string
 		noParam    bool
 	)
 
@@ -194,7 +283,7 @@ func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		return nil, fmt.Errorf("parse error: invalid port")
 	}
 
-	protocol = strings.ToLower(data[3])
+	protocol = shadowSocksRName(data[3])
 	if _, exist := ShadowSocksRProtocolList[protocol]; !exist {
 		return nil, fmt.Errorf("parse error: invalid protocol \"%s\"", protocol)
 	}
@@ -204,9 +293,9 @@ func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		return nil, fmt.Errorf("parse error: invalid cipher \"%s\"", cipher)
 	}
 
-	obfs = strings.ToLower(data[5])
+	obfs = shadowSocksRName(data[5])
 	if _, exist := ShadowSocksRObfsList[obfs]; !exist {
-		return nil, fmt.Errorf("parse error: invalid obfs \"%s\"", cipher)
+		return nil, fmt.Errorf("parse error: invalid obfs \"%s\"", obfs)
 	}
 
 	password, err = util.Base64URLDecode(data[6])
@@ -231,9 +320,14 @@ func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 				return nil, fmt.Errorf("parse error: invalid protoparam")
 			}
 		}
+
+		if params.Has("remarks") {
+			remarks, _ = util.Base64URLDecode(params.Get("remarks"))
+		}
 	}
 
 	proxy = &Proxy{
+		Name:   remarks,
 		Server: server,
 		Port:   port,
 		Schema: "SSR",
@@ -254,5 +348,51 @@ func newShadowSocksR(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		},
 	}
 
+	if proxy.ShadowSocksR.chainTransport() {
+		if _, _, _, err = proxy.ShadowSocksR.chainCipher(); err != nil {
+			return nil, fmt.Errorf("parse error: %v", err)
+		}
+	}
+
 	return
 }
+
+// chainCipher picks the cipher of the clash transport, which lacks some of
+// ShadowSocksRCipherList
+func (ssr *ShadowSocksR) chainCipher() (cipher core.Cipher, ivSize int, key []byte, err error) {
+
+	cipherName := ssr.Cipher
+	if cipherName == "none" {
+		cipherName = "dummy"
+	}
+
+	if cipher, err = core.PickCipher(cipherName, nil, ssr.Password); err != nil {
+		return nil, 0, nil, fmt.Errorf("cipher \"%s\" is not supported with %s", ssr.Cipher, ssr.Protocol.Schema)
+	}
+
+	if streamCipher, ok := cipher.(*core.StreamCipher); ok {
+		return cipher, streamCipher.IVSize(), streamCipher.Key, nil
+	} else if cipherName == "dummy" {
+		return cipher, 0, core.Kdf(ssr.Password, 16), nil
+	}
+
+	return nil, 0, nil, fmt.Errorf("cipher \"%s\" is not a stream cipher", ssr.Cipher)
+}
+
+// chainTransport reports whether the proxy needs shadowSocksRChainDialer
+func (ssr *ShadowSocksR) chainTransport() bool {
+
+	switch ssr.Protocol.Schema {
+	case "auth_chain_a", "auth_chain_b":
+		return true
+	}
+
+	return ssr.Cipher == "none"
+}
+
+// shadowSocksRName lowercases an obfs or protocol name. The "_compatible"
+// variants only let the server fall back to plain, clients speak the same.
+func shadowSocksRName(name string) string {
+
+	return strings.TrimSuffix(strings.ToLower(name), "_compatible")
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	_ "encoding/json"
 	"fmt"
 	"github.com/valyala/fasthttp"
@@ -46,19 +47,96 @@ func socks4Reply(code byte) string {
 	}
 }
 
+// Resolver looks up the targets of the socks proxies that take addresses,
+// net.Resolver implements it
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// NewResolver returns a resolver querying the dns server at addr (host:port)
+func NewResolver(addr string) Resolver {
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// SetResolver replaces the system resolver for the targets of socks5 and socks4
+// proxies
+func (proxy *Proxy) SetResolver(resolver Resolver) {
+
+	proxy.resolver = resolver
+}
+
+// SetRemoteDNS hands the target host names to socks5 proxies, as socks5h
+// does, so no lookup leaves the scanning host. Plain socks4 has no host
+// names, its targets are still resolved locally.
+func (proxy *Proxy) SetRemoteDNS() {
+
+	proxy.remoteDNS = true
+}
+
+// resolvesRemotely reports whether the proxy server looks up the target
+func (proxy *Proxy) resolvesRemotely() bool {
+
+	switch proxy.Schema {
+	case "SOCKS5H", "SOCKS4A":
+		return true
+	case "SOCKS5":
+		return proxy.remoteDNS
+	default:
+		return false
+	}
+}
+
+func (proxy *Proxy) lookupIP(ctx context.Context, network, host string) (net.IP, error) {
+
+	resolver := proxy.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ips, err := resolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+
+	//the proxy may have no ipv6 route, prefer ipv4
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+
+	return ips[0], nil
+}
+
 func (proxy *Proxy) SocksDialer(timeout time.Duration) fasthttp.DialFunc {
 
+	return FastHTTPDialer(context.Background(), timeout, proxy.socksDialer(directDialContext))
+}
+
+func (proxy *Proxy) socksDialer(dial DialContextFunc) DialContextFunc {
+
 	switch proxy.Schema {
 	case "SOCKS4", "SOCKS4A":
-		return proxy.socks4Dialer(timeout)
+		return proxy.socks4Dialer(dial)
 	default:
-		return proxy.socks5Dialer(timeout)
+		return proxy.socks5Dialer(dial)
 	}
 }
 
-func (proxy *Proxy) socks4Dialer(timeout time.Duration) fasthttp.DialFunc {
+func (proxy *Proxy) socks4Dialer(dial DialContextFunc) DialContextFunc {
 
-	return func(addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 
 		var (
 			host string
@@ -82,93 +160,96 @@ func (proxy *Proxy) socks4Dialer(timeout time.Duration) fasthttp.DialFunc {
 		ip := net.ParseIP(host)
 
 		//socks4 protocol does not support ipv6
-		if ip != nil && ip.To16() != nil{
+		if ip != nil && ip.To4() == nil {
 			return nil, fmt.Errorf("socks4 protocol does not support ipv6")
 		}
 
 		//socks4 protocol needs to use ip
-		if ip == nil && proxy.Schema == "SOCKS4" {
-			if ipaddr, err := net.ResolveIPAddr("ip4", host); err != nil {
+		if ip == nil && !proxy.resolvesRemotely() {
+			if ip, err = proxy.lookupIP(ctx, "ip4", host); err != nil {
 				return nil, err
-			} else {
-				ip = ipaddr.IP
 			}
 		}
 
 		//new connect
 		proxyAddr := net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port))
-		if timeout == 0 {
-			conn, err = fasthttp.DialDualStack(proxyAddr)
-		} else {
-			conn, err = fasthttp.DialDualStackTimeout(proxyAddr, timeout)
-		}
+		conn, err = dial(ctx, network, proxyAddr)
 
 		if err != nil {
 			return nil, err
 		}
 
-		//new buf
-		buf := make([]byte, 0, len(host) + 6)
+		if err = handshake(ctx, conn, func() error {
+			return proxy.socks4Connect(conn, host, port, ip)
+		}); err != nil {
+			return nil, err
+		}
 
-		//VER: 4, CMD: 0x01 (CONNECT)
-		buf = append(buf, 0x04, 0x01)
+		return conn, nil
+	}
+}
 
-		//DSTPORT
-		buf = append(buf, byte(port >> 8), byte(port))
+func (proxy *Proxy) socks4Connect(conn net.Conn, host string, port int, ip net.IP) (err error) {
 
-		if proxy.Schema == "SOCKS4" || ip != nil {
-			//socks4(fqdn), socks4(ip), socks4a(ip)
+	//new buf
+	buf := make([]byte, 0, len(host) + 6)
 
-			//DSTIP
-			buf = append(buf, ip.To4()...)
-			//NULL
-			buf = append(buf, 0x00)
-		} else {
-			//socks4a(fqdn)
-
-			//DSTIP: 0x00 0x00 0x00 0x01
-			buf = append(buf, 0x00, 0x00, 0x00, 0x01)
-			//NULL
-			buf = append(buf, 0x00)
-			//FQDN
-			buf = append(buf, host...)
-			//NULL
-			buf = append(buf, 0x00)
-		}
+	//VER: 4, CMD: 0x01 (CONNECT)
+	buf = append(buf, 0x04, 0x01)
 
-		if _, err = conn.Write(buf); err != nil {
-			return nil, err
-		}
+	//DSTPORT
+	buf = append(buf, byte(port >> 8), byte(port))
 
-		//read VN, REP
-		if _, err = io.ReadFull(conn, buf[:2]); err != nil {
-			return nil, err
-		}
+	if ip != nil {
+		//socks4(fqdn), socks4(ip), socks4a(ip)
 
-		//check VN
-		if buf[0] != 0x00 {
-			return nil, fmt.Errorf("invalid protocol version: %d", buf[0])
-		}
+		//DSTIP
+		buf = append(buf, ip.To4()...)
+		//NULL
+		buf = append(buf, 0x00)
+	} else {
+		//socks4a(fqdn)
 
-		//check REP
-		if buf[1] != 0x5A {
-			return nil, fmt.Errorf(socks4Reply(buf[1]))
-		}
+		//DSTIP: 0x00 0x00 0x00 0x01
+		buf = append(buf, 0x00, 0x00, 0x00, 0x01)
+		//NULL
+		buf = append(buf, 0x00)
+		//FQDN
+		buf = append(buf, host...)
+		//NULL
+		buf = append(buf, 0x00)
+	}
 
-		//read DSTPORT, DSTIP
-		if _, err = io.ReadFull(conn, buf[:6]); err != nil {
-			return nil, err
-		}
+	if _, err = conn.Write(buf); err != nil {
+		return err
+	}
 
-		return conn, nil
+	//read VN, REP
+	if _, err = io.ReadFull(conn, buf[:2]); err != nil {
+		return err
+	}
+
+	//check VN
+	if buf[0] != 0x00 {
+		return fmt.Errorf("invalid protocol version: %d", buf[0])
+	}
+
+	//check REP
+	if buf[1] != 0x5A {
+		return fmt.Errorf(socks4Reply(buf[1]))
+	}
 
+	//read DSTPORT, DSTIP
+	if _, err = io.ReadFull(conn, buf[:6]); err != nil {
+		return err
 	}
 
+	return nil
 }
 
-func (proxy *Proxy) socks5Dialer(timeout time.Duration) fasthttp.DialFunc {
+func (proxy *Proxy) socks5Dialer(dial DialContextFunc) DialContextFunc {
 
-	return func(addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 
 		var (
 			host string
@@ -188,172 +269,186 @@ func (proxy *Proxy) socks5Dialer(timeout time.Duration) fasthttp.DialFunc {
 			return nil, fmt.Errorf("port number error: %d", port)
 		}
 
+		//socks5 resolves locally, socks5h leaves it to the proxy
+		if net.ParseIP(host) == nil && !proxy.resolvesRemotely() {
+			ip, err := proxy.lookupIP(ctx, "ip", host)
+			if err != nil {
+				return nil, err
+			}
+			host = ip.String()
+		}
+
 		//new connect
 		proxyAddr := net.JoinHostPort(proxy.Server, strconv.Itoa(proxy.Port))
-		if timeout == 0 {
-			conn, err = fasthttp.DialDualStack(proxyAddr)
-		} else {
-			conn, err = fasthttp.DialDualStackTimeout(proxyAddr, timeout)
-		}
+		conn, err = dial(ctx, network, proxyAddr)
 
 		if err != nil {
 			return nil, err
 		}
 
-		//new buf
-		buf := make([]byte, 0, len(host) + 6)
-
-		//VER 5
-		buf = append(buf, 0x05)
-
-		if !proxy.Auth {
-			//NMETHODS: 1, METHODS: 0x00 (no authentication required)
-			buf = append(buf, 0x01, 0x00)
-		} else {
-			//NMETHODS: 2, METHODS: 0x00, 0x02 (no authentication or username/password)
-			buf = append(buf, 0x02, 0x00, 0x02)
-		}
-
-		if _, err = conn.Write(buf); err != nil {
-			return nil, err
-		}
-
-		if _, err = io.ReadFull(conn, buf[:2]); err != nil {
+		if err = handshake(ctx, conn, func() error {
+			return proxy.socks5Connect(conn, host, port)
+		}); err != nil {
 			return nil, err
 		}
 
-		//check socks version
-		if buf[0] != 0x05 {
-			return nil, fmt.Errorf("invalid protocol version: %d", buf[0])
-		}
-
-		//authentication methods error
-		if buf[1] == 0xFF {
-			return nil, fmt.Errorf("no acceptable authentication methods")
-		}
+		return conn, nil
+	}
+}
 
-		if proxy.Auth && buf[1] != 0x00 {
+func (proxy *Proxy) socks5Connect(conn net.Conn, host string, port int) (err error) {
 
-			if len(proxy.User) == 0 || len(proxy.User) > 255 || len(proxy.Pass) == 0 || len(proxy.User) > 255 {
-				return nil, fmt.Errorf("invalid username/password")
-			}
+	//new buf
+	buf := make([]byte, 0, len(host) + 6)
 
-			//reset buf
-			buf = buf[:0]
+	//VER 5
+	buf = append(buf, 0x05)
 
-			//password protocol version
-			buf = append(buf, 0x01)
-
-			//write username length & var
-			buf = append(buf, byte(len(proxy.User)))
-			buf = append(buf, proxy.User...)
+	if !proxy.Auth {
+		//NMETHODS: 1, METHODS: 0x00 (no authentication required)
+		buf = append(buf, 0x01, 0x00)
+	} else {
+		//NMETHODS: 2, METHODS: 0x00, 0x02 (no authentication or username/password)
+		buf = append(buf, 0x02, 0x00, 0x02)
+	}
 
-			//write password length & var
-			buf = append(buf, byte(len(proxy.Pass)))
-			buf = append(buf, proxy.Pass...)
+	if _, err = conn.Write(buf); err != nil {
+		return err
+	}
 
-			if _, err = conn.Write(buf); err != nil {
-				return nil, err
-			}
+	if _, err = io.ReadFull(conn, buf[:2]); err != nil {
+		return err
+	}
 
-			if _, err = io.ReadFull(conn, buf[:2]); err != nil {
-				return nil, err
-			}
+	//check socks version
+	if buf[0] != 0x05 {
+		return fmt.Errorf("invalid protocol version: %d", buf[0])
+	}
 
-			if buf[0] != 0x01 {
-				return nil, fmt.Errorf("invalid username/password version")
-			}
+	//authentication methods error
+	if buf[1] == 0xFF {
+		return fmt.Errorf("no acceptable authentication methods")
+	}
 
-			if buf[1] != 0x00 {
-				return nil, fmt.Errorf("username/password authentication failed")
-			}
+	if proxy.Auth && buf[1] != 0x00 {
 
+		if len(proxy.User) == 0 || len(proxy.User) > 255 || len(proxy.Pass) == 0 || len(proxy.Pass) > 255 {
+			return fmt.Errorf("invalid username/password")
 		}
 
 		//reset buf
 		buf = buf[:0]
 
-		//CMD: 0x01 (CONNECT)
-		buf = append(buf, 0x05, 0x01, 0)
-
-		if ip := net.ParseIP(host); ip != nil {
-			if ipv4 := ip.To4(); ipv4 != nil {
-				//IPv4
-				buf = append(buf, 0x01)
-				buf = append(buf, ipv4...)
-			} else if ipv6 := ip.To16(); ipv6 != nil {
-				//IPv6
-				buf = append(buf, 0x04)
-				buf = append(buf, ipv6...)
-			} else {
-				return nil, fmt.Errorf("unknown address type: %s", host)
-			}
-		} else {
-			if len(host) > 255 {
-				return nil, fmt.Errorf("FQDN too long")
-			}
-			//FQDN
-			buf = append(buf, 0x03)
-			buf = append(buf, byte(len(host)))
-			buf = append(buf, host...)
-		}
+		//password protocol version
+		buf = append(buf, 0x01)
 
-		//PORT
-		buf = append(buf, byte(port >> 8), byte(port))
+		//write username length & var
+		buf = append(buf, byte(len(proxy.User)))
+		buf = append(buf, proxy.User...)
+
+		//write password length & var
+		buf = append(buf, byte(len(proxy.Pass)))
+		buf = append(buf, proxy.Pass...)
 
 		if _, err = conn.Write(buf); err != nil {
-			return nil, err
+			return err
 		}
 
-		if _, err = io.ReadFull(conn, buf[:4]); err != nil {
-			return nil, err
+		if _, err = io.ReadFull(conn, buf[:2]); err != nil {
+			return err
 		}
 
-		//check socks version
-		if buf[0] != 0x05 {
-			return nil, fmt.Errorf("invalid protocol version(receive): %d", buf[0])
+		if buf[0] != 0x01 {
+			return fmt.Errorf("invalid username/password version")
 		}
 
-		//check REP
 		if buf[1] != 0x00 {
-			return nil, fmt.Errorf(socks5Reply(buf[1]))
+			return fmt.Errorf("username/password authentication failed")
 		}
 
-		//check RSV
-		if buf[2] != 0x00 {
-			return nil, fmt.Errorf("non-zero reserved field")
-		}
+	}
 
-		//bytes to discard (port=2byte)
-		bytesDiscard := 2
-
-		//check ATYP
-		switch buf[3] {
-		case 0x01:
-			bytesDiscard += net.IPv4len
-		case 0x04:
-			bytesDiscard += net.IPv6len
-		case 0x03:
-			if _, err = io.ReadFull(conn, buf[:1]); err != nil {
-				return nil, fmt.Errorf("failed to read domain length: %s", host)
-			}
-			bytesDiscard += int(buf[0])
-		default:
-			return nil, fmt.Errorf("unknown address type(receive): %d", buf[3])
-		}
+	//reset buf
+	buf = buf[:0]
+
+	//CMD: 0x01 (CONNECT)
+	buf = append(buf, 0x05, 0x01, 0)
 
-		if cap(buf) < bytesDiscard {
-			buf = make([]byte, bytesDiscard)
+	if ip := net.ParseIP(host); ip != nil {
+		if ipv4 := ip.To4(); ipv4 != nil {
+			//IPv4
+			buf = append(buf, 0x01)
+			buf = append(buf, ipv4...)
+		} else if ipv6 := ip.To16(); ipv6 != nil {
+			//IPv6
+			buf = append(buf, 0x04)
+			buf = append(buf, ipv6...)
 		} else {
-			buf = buf[:bytesDiscard]
+			return fmt.Errorf("unknown address type: %s", host)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("FQDN too long")
 		}
+		//FQDN
+		buf = append(buf, 0x03)
+		buf = append(buf, byte(len(host)))
+		buf = append(buf, host...)
+	}
 
-		if _, err = io.ReadFull(conn, buf); err != nil {
-			return nil, err
+	//PORT
+	buf = append(buf, byte(port >> 8), byte(port))
+
+	if _, err = conn.Write(buf); err != nil {
+		return err
+	}
+
+	if _, err = io.ReadFull(conn, buf[:4]); err != nil {
+		return err
+	}
+
+	//check socks version
+	if buf[0] != 0x05 {
+		return fmt.Errorf("invalid protocol version(receive): %d", buf[0])
+	}
+
+	//check REP
+	if buf[1] != 0x00 {
+		return fmt.Errorf(socks5Reply(buf[1]))
+	}
+
+	//check RSV
+	if buf[2] != 0x00 {
+		return fmt.Errorf("non-zero reserved field")
+	}
+
+	//bytes to discard (port=2byte)
+	bytesDiscard := 2
+
+	//check ATYP
+	switch buf[3] {
+	case 0x01:
+		bytesDiscard += net.IPv4len
+	case 0x04:
+		bytesDiscard += net.IPv6len
+	case 0x03:
+		if _, err = io.ReadFull(conn, buf[:1]); err != nil {
+			return fmt.Errorf("failed to read domain length: %s", host)
 		}
+		bytesDiscard += int(buf[0])
+	default:
+		return fmt.Errorf("unknown address type(receive): %d", buf[3])
+	}
 
-		return conn, nil
+	if cap(buf) < bytesDiscard {
+		buf = make([]byte, bytesDiscard)
+	} else {
+		buf = buf[:bytesDiscard]
+	}
 
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		return err
 	}
 
-}
\ No newline at end of file
+	return nil
+}
@@ -1,14 +1,10 @@
 package http
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/v2fly/v2ray-core/v4/app/log"
-	"github.com/v2fly/v2ray-core/v4/common/serial"
 	"github.com/valyala/fasthttp"
-	"net"
 	"net/url"
 	"r4scan/http/v2ray/protocol"
 	"r4scan/http/v2ray/stream"
@@ -17,94 +13,12 @@ import (
 	"strings"
 	"time"
 
-	v2ray "github.com/v2fly/v2ray-core/v4"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/inbound"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/outbound"
-	vnet "github.com/v2fly/v2ray-core/v4/common/net"
-	conf "github.com/v2fly/v2ray-core/v4/infra/conf/serial"
 	vdata "r4scan/http/v2ray"
 )
 
 func (proxy *Proxy) TrojanDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	var (
-		config *v2ray.Config
-	)
-
-	configRaw, _ := json.MarshalIndent(map[string][]interface{}{
-		"outbounds": {
-			proxy.Trojan,
-		},
-	}, "", "  ")
-
-	configConf, err := conf.DecodeJSONConfig(bytes.NewReader(configRaw))
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	config, err = configConf.Build()
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	for i, v := range config.App {
-		if v.Type == "v2ray.core.app.log.Config" {
-			config.App[i] = serial.ToTypedMessage(&log.Config{
-				ErrorLogType: 0,
-			})
-			break
-		}
-	}
-
-	return func(addr string) (net.Conn, error) {
-
-		var (
-			host    string
-			port    int
-			portStr string
-			conn    net.Conn
-			err     error
-		)
-
-		host, portStr, err = net.SplitHostPort(addr)
-		if err != nil {
-			return nil, err
-		}
-
-		port, _ = strconv.Atoi(portStr)
-		if port < 1 || port > 65535 {
-			return nil, fmt.Errorf("port number error: %d", port)
-		}
-
-		server, err := v2ray.New(config)
-		if err != nil {
-			return nil, err
-		}
-
-		dest := vnet.TCPDestination(vnet.ParseAddress(host), vnet.Port(port))
-		ctx, cancel := context.WithCancel(context.Background())
-
-		if timeout <= 0 {
-			timeout = time.Second * 5
-		}
-
-		go func() {
-			time.Sleep(timeout)
-			cancel()
-		}()
-
-		conn, err = v2ray.Dial(ctx, server, dest)
-		if err != nil {
-			return nil, err
-		}
-
-		return conn, nil
-	}
-
+	return FastHTTPDialer(context.Background(), timeout, proxy.v2rayDialer(proxy.Trojan))
 }
 
 func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
@@ -113,6 +27,12 @@ func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
 		port          int
 		sni           string
 		allowInsecure bool
+		alpn          []string
+		types         string
+		host          string
+		path          string
+		mux           bool
+		schema        = strings.ToUpper(urls.Scheme)
 	)
 
 	port, err = strconv.Atoi(urls.Port())
@@ -123,7 +43,7 @@ func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
 	trojanUser := protocol.TrojanServers{
 		Address:  urls.Hostname(),
 		Port:     port,
-		Password: urls.User.String(),
+		Password: urls.User.Username(),
 	}
 
 	if err = validator.Validator(trojanUser); err != nil {
@@ -142,20 +62,75 @@ func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
 		sni = urls.Hostname()
 	}
 
-	if urls.Query().Has("allowInsecure") {
-		allowInsecure = true
+	if value := urls.Query().Get("allowInsecure"); value != "" {
+		if allowInsecure, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("parse error: invalid allowInsecure \"%s\"", value)
+		}
+	}
+
+	alpn = splitList(urls.Query().Get("alpn"))
+
+	//trojan-go names plain tcp "original"
+	if types = strings.ToLower(urls.Query().Get("type")); types == "" || types == "original" {
+		types = "tcp"
+	}
+
+	host = strings.TrimSpace(urls.Query().Get("host"))
+	path = strings.TrimSpace(urls.Query().Get("path"))
+
+	if schema == "TROJAN-GO" {
+		if encryption := urls.Query().Get("encryption"); encryption != "" && encryption != "none" {
+			return nil, fmt.Errorf("parse error: unsupported encryption \"%s\"", encryption)
+		}
+		if plugin := urls.Query().Get("plugin"); plugin != "" {
+			return nil, fmt.Errorf("parse error: unsupported plugin \"%s\"", plugin)
+		}
+		if value := urls.Query().Get("mux"); value != "" {
+			if mux, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("parse error: invalid mux \"%s\"", value)
+			}
+		}
 	}
 
 	outBounds := vdata.OutBounds{}
 	outBounds.Protocol = "trojan"
 
 	streamSetting := &vdata.StreamSettings{}
-	streamSetting.Network = "tcp"
+	streamSetting.Network = types
 	streamSetting.Security = "tls"
 
+	switch types {
+	case "tcp":
+		streamSetting.TCPSettings = &stream.TCPSettings{}
+	case "ws":
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("parse error: invalid ws path \"%s\"", path)
+		}
+		if host == "" {
+			host = sni
+		}
+		streamSetting.WSSettings = &stream.WSSettings{}
+		streamSetting.WSSettings.Headers = map[string]string{"Host": host}
+		streamSetting.WSSettings.Path = path
+	case "grpc":
+		if schema == "TROJAN-GO" {
+			return nil, fmt.Errorf("parse error: trojan-go does not support grpc")
+		}
+		if streamSetting.GRPCSettings, err = newGRPCSettings(urls.Query().Get("serviceName"), urls.Query().Get("mode")); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("parse error: invalid stream Type \"%s\"", types)
+	}
+
+	if err = newStreamExtras(streamSetting, urls.Query()); err != nil {
+		return nil, err
+	}
+
 	streamSetting.TLSSettings = &stream.TLSSettings{}
 	streamSetting.TLSSettings.ServerName = sni
 	streamSetting.TLSSettings.AllowInsecure = allowInsecure
+	streamSetting.TLSSettings.Alpn = alpn
 
 	trojanSettings := &protocol.TrojanSettings{
 		TrojanServers: []protocol.TrojanServers{
@@ -168,6 +143,14 @@ func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
 	}
 
 	outBounds.StreamSettings = streamSetting
+
+	//trojan-go multiplexes by itself
+	if schema == "TROJAN" {
+		if outBounds.Mux, err = newMuxSettings(urls.Query()); err != nil {
+			return nil, err
+		}
+	}
+
 	if outBounds.Settings, err = json.Marshal(trojanSettings); err != nil {
 		return nil, err
 	}
@@ -179,10 +162,28 @@ func newTrojan(urls *url.URL) (proxy *Proxy, err error) {
 	proxy = &Proxy{
 		Server: urls.Hostname(),
 		Port:   port,
-		Schema: "TROJAN",
+		Schema: schema,
 		Url:    urls,
 		Trojan: outBounds,
 	}
 
+	if schema == "TROJAN-GO" {
+		proxy.TrojanGo = TrojanGo{
+			Server:        trojanUser.Address,
+			Port:          port,
+			Password:      trojanUser.Password,
+			SNI:           sni,
+			AllowInsecure: allowInsecure,
+			ALPN:          alpn,
+			Mux:           mux,
+		}
+		if types == "ws" {
+			proxy.TrojanGo.WebSocket = &TrojanGoWebSocket{
+				Host: host,
+				Path: path,
+			}
+		}
+	}
+
 	return
 }
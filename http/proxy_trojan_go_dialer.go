@@ -0,0 +1,324 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/sun8911879/shadowsocksR/tools/socks"
+	"github.com/valyala/fasthttp"
+	"github.com/xtaci/smux"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TrojanGo is dialed natively, v2ray neither speaks the trojan-go mux nor
+// lets the trojan connection be tunneled
+type TrojanGo struct {
+	Server        string
+	Port          int
+	Password      string
+	SNI           string
+	AllowInsecure bool
+	ALPN          []string
+	WebSocket     *TrojanGoWebSocket
+	Mux           bool
+}
+
+type TrojanGoWebSocket struct {
+	Host string
+	Path string
+}
+
+const (
+	trojanConnect = 0x01
+	trojanMux     = 0x7f
+
+	//streams per mux session before another one is opened, as trojan-go does
+	trojanGoMuxConcurrency = 8
+)
+
+// trojanGoMux holds the mux sessions of a proxy, every stream of the proxy
+// shares them whatever dial opened the session. opening is the session being
+// opened outside the lock, the other dials wait for it.
+type trojanGoMux struct {
+	mu       sync.Mutex
+	sessions []*smux.Session
+	opening  *trojanGoMuxOpen
+}
+
+type trojanGoMuxOpen struct {
+	done chan struct{}
+	err  error
+}
+
+type webSocketConn struct {
+	*websocket.Conn
+	reader io.Reader
+}
+
+func (proxy *Proxy) TrojanGoDialer(timeout time.Duration) fasthttp.DialFunc {
+
+	return FastHTTPDialer(context.Background(), timeout, proxy.trojanGoDialer(directDialContext))
+}
+
+func (proxy *Proxy) trojanGoDialer(dial DialContextFunc) DialContextFunc {
+
+	if !proxy.TrojanGo.Mux {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return proxy.trojanGoConn(ctx, dial, network, trojanConnect, addr)
+		}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+
+		target := socks.ParseAddr(addr)
+		if target == nil {
+			return nil, fmt.Errorf("trojan-go: invalid address: %s", addr)
+		}
+
+		session, err := proxy.mux.session(ctx, func() (*smux.Session, error) {
+			//the server ignores the address of the mux connection
+			conn, err := proxy.trojanGoConn(ctx, dial, network, trojanMux, "MUX_CONN:0")
+			if err != nil {
+				return nil, err
+			}
+
+			session, err := smux.Client(conn, smux.DefaultConfig())
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+
+			return session, nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := session.OpenStream()
+		if err != nil {
+			return nil, err
+		}
+
+		//every stream starts with the command and the address of the target
+		if err = handshake(ctx, stream, func() error {
+			_, err := stream.Write(append([]byte{trojanConnect}, target...))
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		return stream, nil
+	}
+}
+
+// trojanGoConn opens the tls connection, upgrades it to websocket if needed
+// and sends the trojan request
+func (proxy *Proxy) trojanGoConn(ctx context.Context, dial DialContextFunc, network string, command byte, addr string) (net.Conn, error) {
+
+	target := socks.ParseAddr(addr)
+	if target == nil {
+		return nil, fmt.Errorf("trojan-go: invalid address: %s", addr)
+	}
+
+	proxyAddr := net.JoinHostPort(proxy.TrojanGo.Server, strconv.Itoa(proxy.TrojanGo.Port))
+	conn, err := dial(ctx, network, proxyAddr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel := conn
+
+	if err = handshake(ctx, conn, func() error {
+
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         proxy.TrojanGo.SNI,
+			InsecureSkipVerify: proxy.TrojanGo.AllowInsecure,
+			NextProtos:         proxy.TrojanGo.ALPN,
+		})
+
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+
+		tunnel = tlsConn
+
+		if ws := proxy.TrojanGo.WebSocket; ws != nil {
+			wsConn, err := newWebSocketConn(tlsConn, ws.Host, ws.Path)
+			if err != nil {
+				return err
+			}
+			tunnel = wsConn
+		}
+
+		_, err := tunnel.Write(trojanRequest(proxy.TrojanGo.Password, command, target))
+		return err
+
+	}); err != nil {
+		return nil, err
+	}
+
+	return tunnel, nil
+}
+
+// trojanRequest is the hex sha224 of the password, the command and the socks
+// address of the target, each followed by CRLF
+func trojanRequest(password string, command byte, target socks.Addr) []byte {
+
+	hash := sha256.Sum224([]byte(password))
+
+	buf := make([]byte, 0, hex.EncodedLen(len(hash))+len(target)+5)
+	buf = append(buf, hex.EncodeToString(hash[:])...)
+	buf = append(buf, '\r', '\n', command)
+	buf = append(buf, target...)
+	buf = append(buf, '\r', '\n')
+
+	return buf
+}
+
+// session returns a mux session with room for another stream, open creates a
+// new one when all of them are busy. Only one dial opens a session at a time,
+// it runs without the lock so ctx of the first dial does not hold the others.
+func (m *trojanGoMux) session(ctx context.Context, open func() (*smux.Session, error)) (*smux.Session, error) {
+
+	for {
+		m.mu.Lock()
+
+		sessions := m.sessions[:0]
+		for _, session := range m.sessions {
+			if !session.IsClosed() {
+				sessions = append(sessions, session)
+			}
+		}
+		m.sessions = sessions
+
+		for _, session := range m.sessions {
+			if session.NumStreams() < trojanGoMuxConcurrency {
+				m.mu.Unlock()
+				return session, nil
+			}
+		}
+
+		if opening := m.opening; opening != nil {
+			m.mu.Unlock()
+
+			select {
+			case <-opening.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			//a dial that gave up does not fail the ones waiting for it
+			if opening.err != nil && !errors.Is(opening.err, context.Canceled) && !errors.Is(opening.err, context.DeadlineExceeded) {
+				return nil, opening.err
+			}
+			continue
+		}
+
+		opening := &trojanGoMuxOpen{done: make(chan struct{})}
+		m.opening = opening
+		m.mu.Unlock()
+
+		session, err := open()
+
+		m.mu.Lock()
+		if err == nil {
+			m.sessions = append(m.sessions, session)
+		}
+		m.opening = nil
+		m.mu.Unlock()
+
+		opening.err = err
+		close(opening.done)
+
+		return session, err
+	}
+}
+
+func (m *trojanGoMux) close() error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+
+	for _, session := range m.sessions {
+		if closeErr := session.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	m.sessions = nil
+	return err
+}
+
+func newWebSocketConn(conn net.Conn, host, path string) (*webSocketConn, error) {
+
+	dialer := &websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return conn, nil
+		},
+	}
+
+	//the connection is already tls, the scheme only decides the default port
+	wsUrl := (&url.URL{Scheme: "ws", Host: host}).String() + path
+
+	ws, _, err := dialer.Dial(wsUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webSocketConn{Conn: ws}, nil
+}
+
+func (c *webSocketConn) Read(b []byte) (int, error) {
+
+	for {
+		if c.reader == nil {
+			_, reader, err := c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			c.reader = reader
+		}
+
+		n, err := c.reader.Read(b)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+
+		return n, err
+	}
+}
+
+func (c *webSocketConn) Write(b []byte) (int, error) {
+
+	if err := c.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (c *webSocketConn) SetDeadline(t time.Time) error {
+
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	return c.SetWriteDeadline(t)
+}
@@ -0,0 +1,409 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/v2fly/v2ray-core/v4/app/log"
+	"github.com/v2fly/v2ray-core/v4/common/serial"
+	"net"
+	"net/url"
+	"os"
+	"r4scan/http/v2ray/stream"
+	"r4scan/validator"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v2ray "github.com/v2fly/v2ray-core/v4"
+	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/inbound"
+	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/outbound"
+	vnet "github.com/v2fly/v2ray-core/v4/common/net"
+	conf "github.com/v2fly/v2ray-core/v4/infra/conf/serial"
+	vdata "r4scan/http/v2ray"
+)
+
+// v2rayInstance is the v2ray core of a proxy. The first dial starts it and
+// every later connection shares it until the proxy is closed.
+type v2rayInstance struct {
+	mu       sync.Mutex
+	instance *v2ray.Instance
+}
+
+// v2rayConn ends the dispatch of the connection once it is closed. Connections
+// of the core ignore deadlines, so they are enforced here by closing the
+// connection when a read or write outlasts its deadline.
+type v2rayConn struct {
+	net.Conn
+	cancel        context.CancelFunc
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// Close shuts down the v2ray core and the mux sessions of the proxy, a later
+// dial starts new ones
+func (proxy *Proxy) Close() error {
+
+	err := proxy.core.close()
+	if muxErr := proxy.mux.close(); muxErr != nil && err == nil {
+		err = muxErr
+	}
+
+	return err
+}
+
+// v2rayDialer dispatches connections through the shared core. v2ray connects
+// to the server lazily, so ctx only guards the dispatch and the deadlines of
+// the connection bound the rest.
+func (proxy *Proxy) v2rayDialer(outBounds vdata.OutBounds) DialContextFunc {
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		port, _ := strconv.Atoi(portStr)
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("port number error: %d", port)
+		}
+
+		instance, err := proxy.core.start(outBounds)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dest := vnet.TCPDestination(vnet.ParseAddress(host), vnet.Port(port))
+		connCtx, cancel := context.WithCancel(context.Background())
+
+		conn, err := v2ray.Dial(connCtx, instance, dest)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		return &v2rayConn{Conn: conn, cancel: cancel}, nil
+	}
+}
+
+// SetMux multiplexes up to concurrency connections of a vmess, vless or trojan
+// proxy over one tunnel, a link that sets mux itself keeps its choice. It has
+// to be called before the first dial.
+func (proxy *Proxy) SetMux(concurrency int) {
+
+	var outBounds *vdata.OutBounds
+
+	switch proxy.Schema {
+	case "VMESS":
+		outBounds = &proxy.VMess
+	case "VLESS":
+		outBounds = &proxy.VLess
+	case "TROJAN":
+		outBounds = &proxy.Trojan
+	default:
+		return
+	}
+
+	if outBounds.Mux == nil {
+		outBounds.Mux = &vdata.MuxSettings{
+			Enable:      true,
+			Concurrency: concurrency,
+		}
+	}
+}
+
+// newMuxSettings reads the mux and muxConcurrency parameters of a share link,
+// nil when the link does not mention mux
+func newMuxSettings(query url.Values) (*vdata.MuxSettings, error) {
+
+	value := query.Get("mux")
+	if value == "" {
+		return nil, nil
+	}
+
+	enable, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: invalid mux \"%s\"", value)
+	}
+
+	settings := &vdata.MuxSettings{
+		Enable: enable,
+	}
+
+	if value = query.Get("muxConcurrency"); value != "" && enable {
+		if settings.Concurrency, err = strconv.Atoi(value); err != nil || settings.Concurrency < 1 {
+			return nil, fmt.Errorf("parse error: invalid mux concurrency \"%s\"", value)
+		}
+	}
+
+	if err = validator.Validator(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// newGRPCSettings checks the grpc parameters of a share link, v2ray only
+// speaks the "gun" mode
+func newGRPCSettings(serviceName string, mode string) (*stream.GRPCSettings, error) {
+
+	if mode = strings.ToLower(mode); mode != "" && mode != "gun" {
+		return nil, fmt.Errorf("parse error: unsupported grpc mode \"%s\"", mode)
+	}
+
+	settings := &stream.GRPCSettings{
+		ServiceName: serviceName,
+	}
+
+	if err := validator.Validator(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// newStreamExtras reads the parameters share links add to the stream settings:
+// the headers besides Host, as "Name: Value", the websocket early data and the
+// method of the http header
+func newStreamExtras(settings *vdata.StreamSettings, query url.Values) error {
+
+	headers, err := parseHeaders(query["header"])
+	if err != nil {
+		return err
+	}
+
+	if settings.Network == "tcp" {
+		if settings.TCPSettings == nil || settings.TCPSettings.TCPHeader == nil || settings.TCPSettings.TCPHeader.Request == nil {
+			return nil
+		}
+		request := settings.TCPSettings.TCPHeader.Request
+		request.Method = query.Get("method")
+		for _, header := range headers {
+			if request.Headers == nil {
+				request.Headers = map[string][]string{}
+			}
+			request.Headers[header[0]] = append(request.Headers[header[0]], header[1])
+		}
+		return nil
+	}
+
+	if settings.Network != "ws" {
+		return nil
+	}
+
+	maxEarlyData := query.Get("maxEarlyData")
+	earlyDataHeaderName := query.Get("earlyDataHeaderName")
+
+	if len(headers) == 0 && maxEarlyData == "" && earlyDataHeaderName == "" {
+		return nil
+	}
+
+	if settings.WSSettings == nil {
+		settings.WSSettings = &stream.WSSettings{}
+	}
+
+	for _, header := range headers {
+		if settings.WSSettings.Headers == nil {
+			settings.WSSettings.Headers = map[string]string{}
+		}
+		settings.WSSettings.Headers[header[0]] = header[1]
+	}
+
+	if maxEarlyData != "" {
+		if settings.WSSettings.MaxEarlyData, err = strconv.Atoi(maxEarlyData); err != nil || settings.WSSettings.MaxEarlyData < 0 {
+			return fmt.Errorf("parse error: invalid maxEarlyData \"%s\"", maxEarlyData)
+		}
+	}
+
+	settings.WSSettings.EarlyDataHeaderName = earlyDataHeaderName
+
+	return nil
+}
+
+// parseHeaders splits "Name: Value" parameters into name and value
+func parseHeaders(values []string) ([][2]string, error) {
+
+	var headers [][2]string
+
+	for _, value := range values {
+		name, content, found := strings.Cut(value, ":")
+		if name = strings.TrimSpace(name); !found || name == "" {
+			return nil, fmt.Errorf("parse error: invalid header \"%s\"", value)
+		}
+		headers = append(headers, [2]string{name, strings.TrimSpace(content)})
+	}
+
+	return headers, nil
+}
+
+// splitList splits a comma separated parameter, empty items are dropped
+func splitList(value string) []string {
+
+	var list []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+func newV2rayConfig(outBounds vdata.OutBounds) (*v2ray.Config, error) {
+
+	configRaw, _ := json.MarshalIndent(map[string][]interface{}{
+		"outbounds": {
+			outBounds,
+		},
+	}, "", "  ")
+
+	configConf, err := conf.DecodeJSONConfig(bytes.NewReader(configRaw))
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := configConf.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range config.App {
+		if v.Type == "v2ray.core.app.log.Config" {
+			config.App[i] = serial.ToTypedMessage(&log.Config{
+				ErrorLogType: 0,
+			})
+			break
+		}
+	}
+
+	return config, nil
+}
+
+func (v *v2rayInstance) start(outBounds vdata.OutBounds) (*v2ray.Instance, error) {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.instance != nil {
+		return v.instance, nil
+	}
+
+	config, err := newV2rayConfig(outBounds)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := v2ray.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = instance.Start(); err != nil {
+		_ = instance.Close()
+		return nil, err
+	}
+
+	v.instance = instance
+	return instance, nil
+}
+
+func (v *v2rayInstance) close() error {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.instance == nil {
+		return nil
+	}
+
+	err := v.instance.Close()
+	v.instance = nil
+
+	return err
+}
+
+func (c *v2rayConn) Close() error {
+
+	err := c.Conn.Close()
+	c.cancel()
+
+	return err
+}
+
+func (c *v2rayConn) Read(b []byte) (int, error) {
+
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+
+	return c.withDeadline(deadline, c.Conn.Read, b)
+}
+
+func (c *v2rayConn) Write(b []byte) (int, error) {
+
+	c.mu.Lock()
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+
+	return c.withDeadline(deadline, c.Conn.Write, b)
+}
+
+func (c *v2rayConn) SetDeadline(t time.Time) error {
+
+	c.mu.Lock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *v2rayConn) SetReadDeadline(t time.Time) error {
+
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *v2rayConn) SetWriteDeadline(t time.Time) error {
+
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *v2rayConn) withDeadline(deadline time.Time, op func([]byte) (int, error), b []byte) (int, error) {
+
+	if deadline.IsZero() {
+		return op(b)
+	}
+
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	timer := time.AfterFunc(wait, func() {
+		_ = c.Close()
+	})
+
+	n, err := op(b)
+	if !timer.Stop() {
+		return n, os.ErrDeadlineExceeded
+	}
+
+	return n, err
+}
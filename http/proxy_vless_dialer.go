@@ -1,14 +1,10 @@
 package http
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/v2fly/v2ray-core/v4/app/log"
-	"github.com/v2fly/v2ray-core/v4/common/serial"
 	"github.com/valyala/fasthttp"
-	"net"
 	"net/url"
 	"r4scan/http/v2ray/protocol"
 	"r4scan/http/v2ray/stream"
@@ -17,94 +13,12 @@ import (
 	"strings"
 	"time"
 
-	v2ray "github.com/v2fly/v2ray-core/v4"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/inbound"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/outbound"
-	vnet "github.com/v2fly/v2ray-core/v4/common/net"
-	conf "github.com/v2fly/v2ray-core/v4/infra/conf/serial"
 	vdata "r4scan/http/v2ray"
 )
 
 func (proxy *Proxy) VLessDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	var (
-		config *v2ray.Config
-	)
-
-	configRaw, _ := json.MarshalIndent(map[string][]interface{}{
-		"outbounds": {
-			proxy.VLess,
-		},
-	}, "", "  ")
-
-	configConf, err := conf.DecodeJSONConfig(bytes.NewReader(configRaw))
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	config, err = configConf.Build()
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	for i, v := range config.App {
-		if v.Type == "v2ray.core.app.log.Config" {
-			config.App[i] = serial.ToTypedMessage(&log.Config{
-				ErrorLogType: 0,
-			})
-			break
-		}
-	}
-
-	return func(addr string) (net.Conn, error) {
-
-		var (
-			host    string
-			port    int
-			portStr string
-			conn    net.Conn
-			err     error
-		)
-
-		host, portStr, err = net.SplitHostPort(addr)
-		if err != nil {
-			return nil, err
-		}
-
-		port, _ = strconv.Atoi(portStr)
-		if port < 1 || port > 65535 {
-			return nil, fmt.Errorf("port number error: %d", port)
-		}
-
-		server, err := v2ray.New(config)
-		if err != nil {
-			return nil, err
-		}
-
-		dest := vnet.TCPDestination(vnet.ParseAddress(host), vnet.Port(port))
-		ctx, cancel := context.WithCancel(context.Background())
-
-		if timeout <= 0 {
-			timeout = time.Second * 5
-		}
-
-		go func() {
-			time.Sleep(timeout)
-			cancel()
-		}()
-
-		conn, err = v2ray.Dial(ctx, server, dest)
-		if err != nil {
-			return nil, err
-		}
-
-		return conn, nil
-	}
-
+	return FastHTTPDialer(context.Background(), timeout, proxy.v2rayDialer(proxy.VLess))
 }
 
 func newVLess(urls *url.URL) (proxy *Proxy, err error) {
@@ -137,7 +51,7 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 	}
 
 	if security = strings.ToLower(urls.Query().Get("security")); security == "" {
-		types = "none"
+		security = "none"
 	}
 
 	if hosts := strings.TrimSpace(urls.Query().Get("host")); hosts != "" {
@@ -174,9 +88,8 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 			if len(host) > 0 {
 				streamSetting.TCPSettings.TCPHeader.Request.Headers = map[string][]string{"Host": host}
 			}
-			if path != "" {
-				streamSetting.TCPSettings.TCPHeader.Request.Path = []string{path}
-			}
+			//the paths are comma separated, like the hosts
+			streamSetting.TCPSettings.TCPHeader.Request.Path = splitList(path)
 		}
 	case "kcp":
 		streamSetting.KCPSettings = &stream.KCPSettings{}
@@ -219,10 +132,18 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 			}
 		}
 		streamSetting.QUICSettings.Header = &stream.QUICHeader{Type: headerType}
+	case "grpc":
+		if streamSetting.GRPCSettings, err = newGRPCSettings(urls.Query().Get("serviceName"), urls.Query().Get("mode")); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("parse error: invalid stream Type \"%s\"", types)
 	}
 
+	if err = newStreamExtras(streamSetting, urls.Query()); err != nil {
+		return nil, err
+	}
+
 	switch security {
 	case "tls":
 		streamSetting.Security = "tls"
@@ -232,10 +153,12 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 		} else {
 			streamSetting.TLSSettings.ServerName = urls.Hostname()
 		}
-		if urls.Query().Get("allowInsecure") == "1" {
-			streamSetting.TLSSettings.AllowInsecure = true
+		if value := urls.Query().Get("allowInsecure"); value != "" {
+			if streamSetting.TLSSettings.AllowInsecure, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("parse error: invalid allowInsecure \"%s\"", value)
+			}
 		}
-		streamSetting.TLSSettings.AllowInsecure = true
+		streamSetting.TLSSettings.Alpn = splitList(urls.Query().Get("alpn"))
 	case "none":
 	default:
 		return nil, fmt.Errorf("parse error: invalid security Type \"%s\"", security)
@@ -258,6 +181,10 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 	}
 
 	outBounds.StreamSettings = streamSetting
+	if outBounds.Mux, err = newMuxSettings(urls.Query()); err != nil {
+		return nil, err
+	}
+
 	if outBounds.Settings, err = json.Marshal(vlessSettings); err != nil {
 		return nil, err
 	}
@@ -266,9 +193,6 @@ func newVLess(urls *url.URL) (proxy *Proxy, err error) {
 		return nil, err
 	}
 
-	vv, _ := json.Marshal(outBounds)
-	fmt.Println(string(vv))
-
 	proxy = &Proxy{
 		Server: urls.Hostname(),
 		Port:   port,
@@ -1,14 +1,10 @@
 package http
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/v2fly/v2ray-core/v4/app/log"
-	"github.com/v2fly/v2ray-core/v4/common/serial"
 	"github.com/valyala/fasthttp"
-	"net"
 	"net/url"
 	"r4scan/http/v2ray/protocol"
 	"r4scan/http/v2ray/stream"
@@ -18,94 +14,12 @@ import (
 	"strings"
 	"time"
 
-	v2ray "github.com/v2fly/v2ray-core/v4"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/inbound"
-	_ "github.com/v2fly/v2ray-core/v4/app/proxyman/outbound"
-	vnet "github.com/v2fly/v2ray-core/v4/common/net"
-	conf "github.com/v2fly/v2ray-core/v4/infra/conf/serial"
 	vdata "r4scan/http/v2ray"
 )
 
 func (proxy *Proxy) VMessDialer(timeout time.Duration) fasthttp.DialFunc {
 
-	var (
-		config *v2ray.Config
-	)
-
-	configRaw, _ := json.MarshalIndent(map[string][]interface{}{
-		"outbounds": {
-			proxy.VMess,
-		},
-	}, "", "  ")
-
-	configConf, err := conf.DecodeJSONConfig(bytes.NewReader(configRaw))
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	config, err = configConf.Build()
-	if err != nil {
-		return func(addr string) (net.Conn, error) {
-			return nil, err
-		}
-	}
-
-	for i, v := range config.App {
-		if v.Type == "v2ray.core.app.log.Config" {
-			config.App[i] = serial.ToTypedMessage(&log.Config{
-				ErrorLogType: 0,
-			})
-			break
-		}
-	}
-
-	return func(addr string) (net.Conn, error) {
-
-		var (
-			host    string
-			port    int
-			portStr string
-			conn    net.Conn
-			err     error
-		)
-
-		host, portStr, err = net.SplitHostPort(addr)
-		if err != nil {
-			return nil, err
-		}
-
-		port, _ = strconv.Atoi(portStr)
-		if port < 1 || port > 65535 {
-			return nil, fmt.Errorf("port number error: %d", port)
-		}
-
-		server, err := v2ray.New(config)
-		if err != nil {
-			return nil, err
-		}
-
-		dest := vnet.TCPDestination(vnet.ParseAddress(host), vnet.Port(port))
-		ctx, cancel := context.WithCancel(context.Background())
-
-		if timeout <= 0 {
-			timeout = time.Second * 5
-		}
-
-		go func() {
-			time.Sleep(timeout)
-			cancel()
-		}()
-
-		conn, err = v2ray.Dial(ctx, server, dest)
-		if err != nil {
-			return nil, err
-		}
-
-		return conn, nil
-	}
-
+	return FastHTTPDialer(context.Background(), timeout, proxy.v2rayDialer(proxy.VMess))
 }
 
 func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
@@ -126,6 +40,9 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 
 	streamSetting := &vdata.StreamSettings{}
 	streamSetting.Network = strings.ToLower(link.Net)
+	if streamSetting.Network == "h2" {
+		streamSetting.Network = "http"
+	}
 
 	if link.Host != "" {
 		if strings.Contains(link.Host, ",") {
@@ -149,14 +66,32 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 			if len(host) > 0 {
 				streamSetting.TCPSettings.TCPHeader.Request.Headers = map[string][]string{"Host": host}
 			}
-			if link.Path != "" {
-				streamSetting.TCPSettings.TCPHeader.Request.Path = []string{link.Path}
-			}
+			//the paths are comma separated, like the hosts
+			streamSetting.TCPSettings.TCPHeader.Request.Path = splitList(link.Path)
 		}
 	case "kcp":
-		if link.Type != "" && link.Type != "none" {
+		//v2rayN keeps the seed in path
+		if (link.Type != "" && link.Type != "none") || link.Path != "" {
 			streamSetting.KCPSettings = &stream.KCPSettings{}
-			streamSetting.KCPSettings.Header = &stream.KCPHeader{Type: link.Type}
+			if link.Type != "" && link.Type != "none" {
+				streamSetting.KCPSettings.Header = &stream.KCPHeader{Type: link.Type}
+			}
+			streamSetting.KCPSettings.Seed = link.Path
+		}
+	case "quic":
+		//v2rayN keeps the security in host and the key in path
+		streamSetting.QUICSettings = &stream.QUICSettings{Security: "none"}
+		if security := strings.ToLower(link.Host); security != "" {
+			streamSetting.QUICSettings.Security = security
+		}
+		if streamSetting.QUICSettings.Security != "none" {
+			if link.Path == "" {
+				return nil, fmt.Errorf("parse error: invalid quic key")
+			}
+			streamSetting.QUICSettings.Key = link.Path
+		}
+		if link.Type != "" && link.Type != "none" {
+			streamSetting.QUICSettings.Header = &stream.QUICHeader{Type: link.Type}
 		}
 	case "ws":
 		if len(host) > 0 || link.Path != "" {
@@ -175,14 +110,49 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		}
 		streamSetting.HTTPSettings.Host = host
 		streamSetting.HTTPSettings.Path = link.Path
+	case "grpc":
+		//v2rayN keeps the service name in path and the mode in type
+		mode := link.Type
+		if mode == "none" {
+			mode = ""
+		}
+		if streamSetting.GRPCSettings, err = newGRPCSettings(link.Path, mode); err != nil {
+			return nil, err
+		}
 	}
 
-	if link.TLS == "tls" {
+	switch strings.ToLower(link.TLS) {
+	case "tls":
 		streamSetting.Security = "tls"
-		if link.Host != "" {
-			streamSetting.TLSSettings = &stream.TLSSettings{}
-			streamSetting.TLSSettings.ServerName = link.Host
+		streamSetting.TLSSettings = &stream.TLSSettings{}
+		//without sni the host doubles as the server name, quic keeps its
+		//security there instead
+		if link.SNI != "" {
+			streamSetting.TLSSettings.ServerName = link.SNI
+		} else if len(host) > 0 && streamSetting.Network != "quic" && validator.Var(host[0], "fqdn") == nil {
+			streamSetting.TLSSettings.ServerName = host[0]
+		}
+		if link.ALPN != "" {
+			for _, v := range strings.Split(link.ALPN, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					streamSetting.TLSSettings.Alpn = append(streamSetting.TLSSettings.Alpn, v)
+				}
+			}
 		}
+		//v2ray-core v4 has no tls fingerprint, fp is accepted and ignored
+		streamSetting.TLSSettings.AllowInsecure = link.Insecure()
+	case "", "none":
+	default:
+		return nil, fmt.Errorf("parse error: invalid security Type \"%s\"", link.TLS)
+	}
+
+	security := strings.ToLower(link.Scy)
+	switch security {
+	case "":
+		security = "auto"
+	case "chacha20-ietf-poly1305":
+		//quantumult spells the aead cipher the shadowsocks way
+		security = "chacha20-poly1305"
 	}
 
 	switch link.Aid.(type) {
@@ -222,7 +192,8 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 	vmessUser := protocol.VMessUsers{}
 	vmessUser.ID = link.ID
 	vmessUser.AlterId = alterId
-	vmessUser.Security = "auto"
+	vmessUser.Security = security
+	vmessUser.Experiments = link.Experiments
 
 	vmessVNext := protocol.VMessVNext{}
 	vmessVNext.Address = link.Add
@@ -240,7 +211,38 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 		return nil, err
 	}
 
+	//shadowrocket links carry mux in the query, v2rayN links in the json
+	query := urls.Query()
+	if link.Mux != nil {
+		query.Set("mux", fmt.Sprint(link.Mux))
+	}
+	if link.MuxConcurrency != nil {
+		query.Set("muxConcurrency", fmt.Sprint(link.MuxConcurrency))
+	}
+
+	//so do the stream extras
+	for _, header := range link.Header {
+		query.Add("header", header)
+	}
+	if link.Method != "" {
+		query.Set("method", link.Method)
+	}
+	if link.MaxEarlyData != nil {
+		query.Set("maxEarlyData", fmt.Sprint(link.MaxEarlyData))
+	}
+	if link.EarlyDataHeaderName != "" {
+		query.Set("earlyDataHeaderName", link.EarlyDataHeaderName)
+	}
+
+	if err = newStreamExtras(streamSetting, query); err != nil {
+		return nil, err
+	}
+
 	outBounds.StreamSettings = streamSetting
+	if outBounds.Mux, err = newMuxSettings(query); err != nil {
+		return nil, err
+	}
+
 	if outBounds.Settings, err = json.Marshal(vmessSettings); err != nil {
 		return nil, err
 	}
@@ -250,6 +252,7 @@ func newVMess(urls *url.URL, rawUrl string) (proxy *Proxy, err error) {
 	}
 
 	proxy = &Proxy{
+		Name:   link.Ps,
 		Server: link.Add,
 		Port:   port,
 		Schema: "VMESS",
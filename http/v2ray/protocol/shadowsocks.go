@@ -0,0 +1,13 @@
+package protocol
+
+type ShadowSocksSettings struct {
+	ShadowSocksServers []ShadowSocksServers `json:"servers" validate:"required,dive"`
+}
+
+type ShadowSocksServers struct {
+	Address  string `json:"address" validate:"required,fqdn|ip" errMsg:"invalid shadowsocks address"`
+	Port     int    `json:"port" validate:"required,min=1,max=65535" errMsg:"invalid shadowsocks port"`
+	Method   string `json:"method" validate:"required" errMsg:"invalid shadowsocks method"`
+	Password string `json:"password" validate:"required" errMsg:"invalid shadowsocks password"`
+	Level    int    `json:"level,omitempty" validate:"omitempty,min=0" errMsg:"invalid shadowsocks userLevel"`
+}
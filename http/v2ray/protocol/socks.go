@@ -0,0 +1,19 @@
+package protocol
+
+//http outbounds share the socks server layout
+
+type SocksSettings struct {
+	SocksServers []SocksServers `json:"servers" validate:"required,dive"`
+}
+
+type SocksServers struct {
+	Address string       `json:"address" validate:"required,fqdn|ip" errMsg:"invalid socks address"`
+	Port    int          `json:"port" validate:"required,min=1,max=65535" errMsg:"invalid socks port"`
+	Users   []SocksUsers `json:"users,omitempty" validate:"omitempty,dive"`
+}
+
+type SocksUsers struct {
+	User  string `json:"user" validate:"required" errMsg:"invalid socks user"`
+	Pass  string `json:"pass"`
+	Level int    `json:"level,omitempty" validate:"omitempty,min=0" errMsg:"invalid socks userLevel"`
+}
@@ -0,0 +1,5 @@
+package stream
+
+type GRPCSettings struct {
+	ServiceName string `json:"serviceName" validate:"required,excludesall=/" errMsg:"invalid grpc serviceName"`
+}
@@ -14,8 +14,9 @@ var VMessCipherList = map[string]struct{}{
 }
 
 type OutBounds struct {
+	Tag            string          `json:"tag,omitempty"`
 	SendThrough    string          `json:"sendThrough,omitempty" validate:"omitempty,ip" errMsg:"invalid sendThrough"`
-	Protocol       string          `json:"protocol" validate:"required,oneof=vmess vless trojan" errMsg:"invalid protocol"`
+	Protocol       string          `json:"protocol" validate:"required,oneof=vmess vless trojan shadowsocks socks http" errMsg:"invalid protocol"`
 	Settings       json.RawMessage `json:"settings" validate:"required" errMsg:"invalid protocol setting"`
 	StreamSettings *StreamSettings `json:"streamSettings,omitempty"`
 	Mux            *MuxSettings    `json:"mux,omitempty"`
@@ -26,7 +27,7 @@ type OutBounds struct {
 //VMessSettings  *protocol.VMessSettings  `json:"settings,omitempty" validate:"required_if=Protocol vmess" errMsg:"invalid vmess setting"`
 
 type StreamSettings struct {
-	Network      string               `json:"network,omitempty" validate:"omitempty,oneof=tcp kcp ws http quic" errMsg:"invalid stream network"`
+	Network      string               `json:"network,omitempty" validate:"omitempty,oneof=tcp kcp ws http quic grpc" errMsg:"invalid stream network"`
 	Security     string               `json:"security,omitempty" validate:"omitempty,oneof=none tls" errMsg:"invalid stream security"`
 	TLSSettings  *stream.TLSSettings  `json:"tlsSettings,omitempty" validate:"omitempty,excluded_unless=Security tls" errMsg:"invalid stream tlsSettings"`
 	TCPSettings  *stream.TCPSettings  `json:"tcpSettings,omitempty" validate:"omitempty,excluded_unless=Network tcp|required_without_all=Network" errMsg:"invalid stream tcpSettings"`
@@ -34,9 +35,10 @@ type StreamSettings struct {
 	WSSettings   *stream.WSSettings   `json:"wsSettings,omitempty" validate:"omitempty,excluded_unless=Network ws" errMsg:"invalid stream wsSettings"`
 	HTTPSettings *stream.HTTPSettings `json:"httpSettings,omitempty" validate:"omitempty,excluded_unless=Network http" errMsg:"invalid stream httpSettings"`
 	QUICSettings *stream.QUICSettings `json:"quicSettings,omitempty" validate:"omitempty,excluded_unless=Network quic" errMsg:"invalid stream quicSettings"`
+	GRPCSettings *stream.GRPCSettings `json:"grpcSettings,omitempty" validate:"required_if=Network grpc,omitempty,excluded_unless=Network grpc" errMsg:"invalid stream grpcSettings"`
 }
 
 type MuxSettings struct {
-	Enable      bool `json:"enable,omitempty"`
+	Enable      bool `json:"enabled,omitempty"`
 	Concurrency int  `json:"concurrency,omitempty" validate:"omitempty,min=-1,max=1024" errMsg:"invalid mux concurrency"`
 }
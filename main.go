@@ -1,22 +1,22 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"github.com/PuerkitoBio/goquery"
 	"github.com/alexflint/go-arg"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"io"
-	"log"
+	"net"
 	"os"
 	"r4scan/core"
 	proto2 "r4scan/core/local"
 	"r4scan/http"
+	"r4scan/util"
 	"r4scan/validator"
 	"runtime"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -32,9 +32,132 @@ func main() {
 		os.Exit(0)
 	}
 
+	if args.GenCert != "" {
+		genCert()
+	}
+
+	if args.ProxyExport != "" {
+		exportProxies()
+	}
+
+	if args.Serve != "" {
+		startServe()
+	}
+
+	if args.Node {
+		startNode()
+	}
+
 	startUp()
 }
 
+func genCert() {
+
+	if err := validator.Validator(&args.SecurityOption); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err := util.GenerateCertificates(args.GenCert, args.CertHost); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("certificates written to %s\n", args.GenCert)
+	os.Exit(0)
+}
+
+func startNode() {
+
+	if err := validator.Var(args.NodePort, "omitempty,min=1,max=65535"); err != nil {
+		fmt.Println("invalid node port (Limit range: 1-65535)")
+		os.Exit(1)
+	}
+
+	if err := validator.Validator(&args.SecurityOption); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err := core.RunAsNode(args.NodePort, newSecurity(), func(addr string) {
+		fmt.Printf("node listening on %s\n", addr)
+	}, printDropped)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+func startServe() {
+
+	if err := validator.Var(args.Serve, "hostname_port"); err != nil {
+		fmt.Println("invalid serve address (Example: 127.0.0.1:1080)")
+		os.Exit(1)
+	}
+
+	user, pass, _ := strings.Cut(args.ServeAuth, ":")
+	if args.ServeAuth != "" && (user == "" || len(user) > 255 || pass == "" || len(pass) > 255) {
+		fmt.Println("invalid serve auth (Example: user:pass)")
+		os.Exit(1)
+	}
+
+	//anyone reaching the port could relay through the proxies
+	if host, _, _ := net.SplitHostPort(args.Serve); args.ServeAuth == "" && !isLoopback(host) {
+		fmt.Println("serving on a non-loopback address requires --serve-auth")
+		os.Exit(1)
+	}
+
+	if err := validator.Validator(&args.ProxyOption); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	loadProxies()
+
+	server := http.NewProxyServer().
+		SetTimeout(time.Duration(args.Timeout)*time.Millisecond).
+		SetAuth(user, pass).
+		SetLogger(printRelayed)
+	request := newCreateRequest()
+
+	chain, err := core.NewProxyChain(request)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if len(args.Proxy) > 0 {
+		pool, dead, err := core.NewProxyPool(request, chain)
+		for _, result := range dead {
+			printDropped(result)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		server.SetProxyPool(pool)
+	} else if chain != nil {
+		server.SetProxyChain(chain)
+	} else {
+		fmt.Println("no proxy to serve")
+		os.Exit(1)
+	}
+
+	err = server.ListenAndServe(args.Serve, func(addr string) {
+		fmt.Printf("serving proxies on %s\n", addr)
+	})
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
 func startUp() {
 
 	if err := validator.Validator(&args); err != nil {
@@ -42,15 +165,20 @@ func startUp() {
 		os.Exit(1)
 	}
 
-	//aaa := make(chan map[string]string)
+	loadProxies()
+
+	if len(args.Nodes) > 0 {
+		startController()
+		return
+	}
 
-	_, addr, err := core.RunAsLocal()
+	server, addr, err := core.RunAsLocal(printDropped)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	fmt.Println(addr)
+	defer server.Stop()
 
 	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -58,9 +186,11 @@ func startUp() {
 		os.Exit(1)
 	}
 
+	defer conn.Close()
+
 	client := proto2.NewR4ScanClient(conn)
 
-	r, err := client.Create(context.Background(), &proto2.CreateRequest{Test: "aaaa"})
+	r, err := client.Create(context.Background(), newCreateRequest())
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -72,122 +202,230 @@ func startUp() {
 			break
 		}
 		if err != nil {
-			log.Fatalf("ListStr get stream err: %v", err)
+			fmt.Println(err)
+			os.Exit(1)
 		}
-		fmt.Println(res.String())
-	}
-
-	fmt.Println(111)
-
-	//fmt.Println(r.String())
-	//
-	//r, err = client.Create(context.Background(), &proto2.CreateRequest{Test: "aaaa"})
-	//if err != nil {
-	//	fmt.Println(err)
-	//	os.Exit(1)
-	//}
-	//
-	//fmt.Println(r.String())
-
-	//	var datas = `
-	//<html>
-	//<body>
-	//	<table>
-	//    <tr>
-	//      <td>
-	//          <!-- comment #1 -->
-	//          Name
-	//      </td>
-	//      <td>
-	//        <span style="text-decoration:line-through">
-	//            <!-- comment #2 -->
-	//            Name
-	//        </span>
-	//      </td>
-	//			<td>
-	//				<div>
-	//					<p>
-	//						Some content
-	//						<!-- comment #3 -->
-	//					</p>
-	//				</div>
-	//			</td>
-	//    </tr>
-	//	</table>
-	//</body>
-	//</html>
-	//`
-
-	proxys, err := http.NewProxy("vless://4ce4eec8-6319-4af3-bcad-91658a176445@127.0.0.1:9999?encryption=none&security=tls&sni=9.com&type=tcp&headerType=none#a\n")
+		printReply(res)
+	}
+}
+
+func startController() {
+
+	options, err := newSecurity().DialOptions()
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	hclient := http.NewClient().SetCertificateVerify(true).SetProxy(proxys)
-	resp, err := hclient.Do(args.URL[0])
+	report, err := core.NewController(args.Nodes).SetDialOptions(options...).SetLogger(printDropped).Run(context.Background(), newCreateRequest(), func(node string, reply *proto2.CreateReply) {
+		printReply(reply)
+	})
 
-	defer http.ReleaseResponse(resp)
+	if report != nil {
+		fmt.Printf("\n%d hits\n", report.Hits)
+		for _, node := range report.Nodes {
+			if node.Err != nil {
+				fmt.Printf("- node %s: %d shards, %d hits, failed: %v\n", node.Addr, node.Shards, node.Hits, node.Err)
+			} else {
+				fmt.Printf("- node %s: %d shards, %d hits\n", node.Addr, node.Shards, node.Hits)
+			}
+		}
+	}
 
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
+}
 
-	fmt.Println(string(resp.Body()))
+func loadProxies() {
 
-	os.Exit(1)
+	var (
+		proxies []*http.Proxy
+		errs    []error
+	)
 
-	//res, _ := http.Get("https://github.com/PuerkitoBio/goquery")
+	if args.ProxyFile == "" && len(args.ProxyURL) == 0 {
+		return
+	}
 
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+	if args.ProxyFile != "" {
+		loaded, loadErrs := http.LoadProxyFile(args.ProxyFile)
+		proxies = append(proxies, loaded...)
+		errs = append(errs, loadErrs...)
+	}
 
-	if err != nil {
-		log.Fatal(err)
+	for _, proxyUrl := range args.ProxyURL {
+		loaded, loadErrs := http.LoadProxyURL(proxyUrl, time.Duration(args.Timeout)*time.Millisecond)
+		proxies = append(proxies, loaded...)
+		errs = append(errs, loadErrs...)
 	}
 
-	//fmt.Println(doc.Next().Length())
+	for _, err := range errs {
+		fmt.Printf("proxy skipped: %v\n", err)
+	}
 
-	//fmt.Println(doc.Children().Length())
-	ii := 0
-	doc.Find("*").Contents().Each(func(i int, selection *goquery.Selection) {
-		ii++
-		//fmt.Println(goquery.NodeName(selection))
-		for _, a := range selection.Get(0).Attr {
-			if strings.Contains(a.Key, "action") {
-				fmt.Printf(`%s="%s"`+"\n", a.Key, a.Val)
-			}
+	if len(proxies) == 0 {
+		fmt.Println("no proxy loaded")
+		os.Exit(1)
+	}
+
+	var (
+		exist = map[string]struct{}{}
+		added int
+	)
 
+	for _, proxy := range args.Proxy {
+		exist[proxy] = struct{}{}
+	}
+
+	for _, proxy := range proxies {
+		if _, ok := exist[proxy.Raw]; !ok {
+			exist[proxy.Raw] = struct{}{}
+			args.Proxy = append(args.Proxy, proxy.Raw)
+			added++
 		}
+	}
+
+	fmt.Printf("%d proxies loaded\n", added)
+}
+
+func exportProxies() {
+
+	if err := validator.Validator(&args.ProxyOption); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	loadProxies()
+
+	var (
+		proxies []*http.Proxy
+		request = newCreateRequest()
+	)
 
-		if selection.Contents().Length() == 0 {
-			//for _, a := range selection.Get(0).Attr {
-			//	fmt.Printf(`%s="%s"`+"\n", a.Key, a.Val)
-			//}
-			//fmt.Println(goquery.NodeName(selection))
-			//fmt.Println("haha:" + strings.TrimSpace(selection.Text()))
-			//fmt.Println("")
+	for _, rawUrl := range args.Proxy {
+		proxy, err := core.NewProxy(request, rawUrl)
+		if err != nil {
+			fmt.Printf("invalid proxy \"%s\": %v\n", rawUrl, err)
+			os.Exit(1)
 		}
+		proxies = append(proxies, proxy)
+	}
 
-	})
+	if len(proxies) == 0 {
+		fmt.Println("no proxy to export")
+		os.Exit(1)
+	}
 
-	fmt.Println(ii)
+	if args.ProxyCheck {
+		chain, err := core.NewProxyChain(request)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-	doc.Each(func(i int, selection *goquery.Selection) {
-		//fmt.Println(selection.Html())
-	})
+		alive, dead, err := core.CheckProxies(request, proxies, chain)
+		if chain != nil {
+			_ = chain.Close()
+		}
 
-	doc.Each(func(i int, selection *goquery.Selection) {
-		//fmt.Println(selection.NextAll().Length())
-	})
+		for _, result := range dead {
+			printDropped(result)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		proxies = proxies[:0]
+		for _, result := range alive {
+			proxies = append(proxies, result.Proxy)
+		}
+	}
+
+	data, errs := http.ExportProxies(proxies, args.ProxyExportFormat)
+	for _, err := range errs {
+		fmt.Printf("proxy not exported: %v\n", err)
+	}
+
+	if err := os.WriteFile(args.ProxyExport, data, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d proxies written to %s\n", len(proxies)-len(errs), args.ProxyExport)
+	os.Exit(0)
+}
+
+func newSecurity() *core.Security {
+	return &core.Security{
+		CertFile: args.TLSCert,
+		KeyFile:  args.TLSKey,
+		CAFile:   args.TLSCA,
+		Token:    args.Token,
+		Insecure: args.TokenInsecure,
+	}
+}
+
+func newCreateRequest() *proto2.CreateRequest {
+	return &proto2.CreateRequest{
+		Url:              args.URL,
+		Method:           args.Method,
+		Timeout:          int32(args.Timeout),
+		Delay:            int32(args.Delay),
+		Retry:            int32(args.Retry),
+		UserAgent:        args.UserAgent,
+		XForwardedFor:    args.XForwardedFor,
+		Header:           args.Header,
+		Body:             args.Body,
+		Status:           args.Status,
+		Ignore:           args.Ignore,
+		Required:         args.Required,
+		Dict:             args.Dict,
+		DictPath:         args.DictPath,
+		DictComment:      args.DictComment,
+		Ext:              args.Ext,
+		Variable:         args.Variable,
+		Thread:           int32(args.Thread),
+		MaxSpeed:         int32(args.MaxSpeed),
+		Proxy:            args.Proxy,
+		ProxyStrategy:    args.ProxyStrategy,
+		ProxyCheck:       args.ProxyCheck,
+		ProxyCheckUrl:    args.ProxyCheckURL,
+		ProxyCheckStatus: int32(args.ProxyCheckStatus),
+		ProxyCheckBody:   args.ProxyCheckBody,
+		ProxyMaxLatency:  int32(args.ProxyMaxLatency),
+		ProxyChain:       args.ProxyChain,
+		ProxyMux:         int32(args.ProxyMux),
+		ProxyDns:         args.ProxyDNS,
+	}
+}
+
+func printReply(reply *proto2.CreateReply) {
+
+	fmt.Printf("[%d] %8d  %s  (%s)\n", reply.Status, reply.Length, reply.Url, reply.Rule)
+}
+
+func isLoopback(host string) bool {
 
-	//x, _ := doc.Html()
-	//
-	//fmt.Println(x)
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
 
-	//for {
-	//}
+func printRelayed(client, target, upstream string, err error) {
+
+	if err != nil {
+		fmt.Printf("%s -> %s via %s failed: %v\n", client, target, upstream, err)
+	} else {
+		fmt.Printf("%s -> %s via %s\n", client, target, upstream)
+	}
+}
 
-	fmt.Println(1)
+func printDropped(result *http.CheckResult) {
 
+	fmt.Printf("proxy dropped: %s (%v)\n", result.Proxy.String(), result.Err)
 }
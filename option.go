@@ -21,9 +21,20 @@ type RequestOption struct {
 }
 
 type ProxyOption struct {
-	Proxy     []string `help:"Sending requests using a proxy" validate:"omitempty,unique,dive,url" errMsg:"invalid proxy"`
-	ProxyFile string   `arg:"--proxy-file" help:"Load proxy from file" validate:"omitempty,min=1,max=100" errMsg:"invalid proxyFile (String length limit range: 1-100)"`
-	ProxyURL  []string `arg:"--proxy-url" help:"Load proxy from URL" validate:"omitempty,unique,dive,url" errMsg:"invalid proxyURL"`
+	Proxy             []string `help:"Sending requests using a proxy" validate:"omitempty,unique,dive,url" errMsg:"invalid proxy"`
+	ProxyFile         string   `arg:"--proxy-file" help:"Load proxy from file" validate:"omitempty,min=1,max=100" errMsg:"invalid proxyFile (String length limit range: 1-100)"`
+	ProxyURL          []string `arg:"--proxy-url" help:"Load proxy from URL" validate:"omitempty,unique,dive,url" errMsg:"invalid proxyURL"`
+	ProxyStrategy     string   `arg:"--proxy-strategy" default:"round-robin" help:"How proxies are rotated (round-robin, random, latency, sticky)" validate:"omitempty,oneof=round-robin random latency sticky" errMsg:"invalid proxyStrategy (round-robin, random, latency, sticky)"`
+	ProxyCheck        bool     `arg:"--proxy-check" help:"Test proxies before the scan and drop dead or slow ones"`
+	ProxyCheckURL     string   `arg:"--proxy-check-url" default:"http://www.gstatic.com/generate_204" help:"URL requested by the proxy check" validate:"omitempty,url" errMsg:"invalid proxyCheckURL"`
+	ProxyCheckStatus  int      `arg:"--proxy-check-status" default:"204" help:"Expected status code of the proxy check" validate:"omitempty,min=100,max=599" errMsg:"invalid proxyCheckStatus (Limit range: 100-599)"`
+	ProxyCheckBody    string   `arg:"--proxy-check-body" help:"Keyword expected in the proxy check response" validate:"omitempty,min=1,max=100" errMsg:"invalid proxyCheckBody (String length limit range: 1-100)"`
+	ProxyMaxLatency   int      `arg:"--proxy-max-latency" default:"0" help:"Drop proxies slower than this (ms), 0 for no limit" validate:"omitempty,min=0,max=120000" errMsg:"invalid proxyMaxLatency (Limit range: 0-120000)"`
+	ProxyChain        []string `arg:"--proxy-chain" help:"Reach the proxies, or the target without --proxy, through these proxies in order" validate:"omitempty,dive,url" errMsg:"invalid proxyChain"`
+	ProxyMux          int      `arg:"--proxy-mux" placeholder:"CONCURRENCY" default:"0" help:"Multiplex up to CONCURRENCY connections over one tunnel of vmess, vless and trojan proxies, 0 to leave it to the links" validate:"omitempty,min=0,max=1024" errMsg:"invalid proxyMux (Limit range: 0-1024)"`
+	ProxyDNS          string   `arg:"--proxy-dns" placeholder:"SERVER" help:"DNS server (host:port) resolving the targets of socks5 and socks4 proxies, or \"remote\" to leave it to socks5 proxies as socks5h does" validate:"omitempty,eq=remote|hostname_port" errMsg:"invalid proxyDNS (remote or host:port)"`
+	ProxyExport       string   `arg:"--proxy-export" placeholder:"FILE" help:"Write the loaded (and checked) proxies to FILE and exit" validate:"omitempty,min=1,max=100" errMsg:"invalid proxyExport (String length limit range: 1-100)"`
+	ProxyExportFormat string   `arg:"--proxy-export-format" default:"link" help:"Format of --proxy-export (link, base64, clash, v2ray)" validate:"omitempty,oneof=link base64 clash v2ray" errMsg:"invalid proxyExportFormat (link, base64, clash, v2ray)"`
 }
 
 type ResponseOption struct {
@@ -33,8 +44,19 @@ type ResponseOption struct {
 }
 
 type DictOption struct {
-	Dict     []string          `arg:"-d,--dict" help:"Load the specified dictionary" validate:"omitempty,unique,dive,min=1,max=100" errMsg:"invalid dict (String length limit range: 1-100)"`
-	DictPath string            `arg:"--dict-path" default:"dict" help:"Load dictionary from specified path" validate:"omitempty,min=1,max=100" errMsg:"invalid dictPath (String length limit range: 1-100)"`
-	Ext      []string          `arg:"-x,--extension" help:"Set extension" validate:"omitempty,unique,dive,min=1,max=20" errMsg:"invalid ext (String length limit range: 1-20)"`
-	Variable map[string]string `arg:"-v,--variable" help:"Custom Variable" validate:"omitempty,dive,keys,min=1,max=100,ne=ext,ne=EXT,endkeys,min=1" errMsg:"invalid variable (Example: key=value, \"key\"=\"value\")"`
+	Dict        []string          `arg:"-d,--dict" help:"Load the specified dictionary" validate:"omitempty,unique,dive,min=1,max=100" errMsg:"invalid dict (String length limit range: 1-100)"`
+	DictPath    string            `arg:"--dict-path" default:"dict" help:"Load dictionary from specified path" validate:"omitempty,min=1,max=100" errMsg:"invalid dictPath (String length limit range: 1-100)"`
+	DictComment string            `arg:"--dict-comment" placeholder:"PREFIX" help:"Skip dictionary lines starting with PREFIX, e.g. #" validate:"omitempty,min=1,max=10" errMsg:"invalid dictComment (String length limit range: 1-10)"`
+	Ext         []string          `arg:"-x,--extension" help:"Set extension" validate:"omitempty,unique,dive,min=1,max=20" errMsg:"invalid ext (String length limit range: 1-20)"`
+	Variable    map[string]string `arg:"-v,--variable" help:"Custom Variable" validate:"omitempty,dive,keys,min=1,max=100,ne=ext,ne=EXT,endkeys,min=1" errMsg:"invalid variable (Example: key=value, \"key\"=\"value\")"`
+}
+
+type SecurityOption struct {
+	TLSCert       string   `arg:"--tls-cert" help:"Certificate presented by the node or the controller" validate:"required_with=TLSKey,omitempty,file" errMsg:"invalid tlsCert (File not found)"`
+	TLSKey        string   `arg:"--tls-key" help:"Private key of --tls-cert" validate:"required_with=TLSCert,omitempty,file" errMsg:"invalid tlsKey (File not found)"`
+	TLSCA         string   `arg:"--tls-ca" help:"CA used to verify the peer certificate" validate:"omitempty,file" errMsg:"invalid tlsCA (File not found)"`
+	Token         string   `arg:"--token" help:"Bearer token required on node channels" validate:"omitempty,min=8,max=256" errMsg:"invalid token (String length limit range: 8-256)"`
+	TokenInsecure bool     `arg:"--token-insecure" help:"Allow sending --token over a plaintext channel"`
+	GenCert       string   `arg:"--gen-cert" placeholder:"DIR" help:"Generate a self-signed CA with node and client certificates into DIR and exit" validate:"omitempty,min=1,max=100" errMsg:"invalid genCert (String length limit range: 1-100)"`
+	CertHost      []string `arg:"--cert-host" help:"Hosts of the generated node certificate [default: localhost 127.0.0.1]" validate:"omitempty,unique,dive,hostname|ip" errMsg:"invalid certHost"`
 }
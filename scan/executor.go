@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"context"
+	"github.com/valyala/fasthttp"
+	"r4scan/http"
+	"sync"
+	"time"
+)
+
+type Executor struct {
+	client  *http.Client
+	thread  int
+	limiter *Limiter
+	delay   time.Duration
+}
+
+// Handler receives every finished request, the response is released after it returns
+type Handler func(url string, response *fasthttp.Response, err error)
+
+func NewExecutor(client *http.Client) *Executor {
+	return &Executor{
+		client: client,
+		thread: 20,
+	}
+}
+
+func (e *Executor) SetThread(thread int) *Executor {
+
+	if thread > 0 {
+		e.thread = thread
+	}
+	return e
+}
+
+func (e *Executor) SetMaxSpeed(maxSpeed int) *Executor {
+
+	e.limiter = NewLimiter(maxSpeed)
+	return e
+}
+
+func (e *Executor) SetDelay(delay time.Duration) *Executor {
+
+	e.delay = delay
+	return e
+}
+
+// Run consumes urls with the configured number of workers until the channel
+// is closed or ctx is cancelled
+func (e *Executor) Run(ctx context.Context, urls <-chan string, handler Handler) {
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < e.thread; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.worker(ctx, urls, handler)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (e *Executor) worker(ctx context.Context, urls <-chan string, handler Handler) {
+
+	for {
+
+		var (
+			url string
+			ok  bool
+		)
+
+		select {
+		case url, ok = <-urls:
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		if err := e.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		response, err := e.client.Do(url)
+		handler(url, response, err)
+		http.ReleaseResponse(response)
+
+		if e.delay > 0 {
+			timer := time.NewTimer(e.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}
+}
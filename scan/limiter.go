@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by all workers of an executor
+type Limiter struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func NewLimiter(rate int) *Limiter {
+
+	if rate <= 0 {
+		return nil
+	}
+
+	//allow short bursts of at most a tenth of a second
+	capacity := float64(rate) / 10
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &Limiter{
+		rate:     float64(rate),
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+func (l *Limiter) Wait(ctx context.Context) error {
+
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	l.last = now
+
+	//reserve a token, the balance may go negative while callers are queued
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strconv"
+)
+
+var DefaultStatus = []string{"200", "301", "302", "401", "403"}
+
+type Matcher struct {
+	status   map[int]struct{}
+	ignore   [][]byte
+	required [][]byte
+}
+
+// Match tells whether a response is a hit and which rule decided it
+type Match struct {
+	Hit    bool
+	Status int
+	Rule   string
+}
+
+func (m Match) String() string {
+
+	if m.Hit {
+		return "accepted: " + m.Rule
+	}
+	return "rejected: " + m.Rule
+}
+
+func NewMatcher() *Matcher {
+
+	return (&Matcher{}).SetStatus(DefaultStatus)
+}
+
+func (m *Matcher) SetStatus(status []string) *Matcher {
+
+	if len(status) == 0 {
+		return m
+	}
+
+	m.status = make(map[int]struct{}, len(status))
+	for _, v := range status {
+		if code, err := strconv.Atoi(v); err == nil {
+			m.status[code] = struct{}{}
+		}
+	}
+
+	return m
+}
+
+func (m *Matcher) SetIgnore(ignore []string) *Matcher {
+
+	for _, v := range ignore {
+		m.ignore = append(m.ignore, []byte(v))
+	}
+	return m
+}
+
+func (m *Matcher) SetRequired(required []string) *Matcher {
+
+	for _, v := range required {
+		m.required = append(m.required, []byte(v))
+	}
+	return m
+}
+
+// Match checks the status code first, then the ignore keywords and finally
+// the required keywords. Keywords are searched in the response header and
+// the decoded body.
+func (m *Matcher) Match(response *fasthttp.Response) Match {
+
+	code := response.StatusCode()
+
+	if _, exist := m.status[code]; !exist {
+		return Match{Status: code, Rule: fmt.Sprintf("status %d not accepted", code)}
+	}
+
+	if len(m.ignore) == 0 && len(m.required) == 0 {
+		return Match{Hit: true, Status: code, Rule: fmt.Sprintf("status %d", code)}
+	}
+
+	header := response.Header.Header()
+	body, err := response.BodyUncompressed()
+	if err != nil {
+		body = response.Body()
+	}
+
+	for _, keyword := range m.ignore {
+		if bytes.Contains(header, keyword) || bytes.Contains(body, keyword) {
+			return Match{Status: code, Rule: fmt.Sprintf("ignore keyword \"%s\" found", keyword)}
+		}
+	}
+
+	for _, keyword := range m.required {
+		if !bytes.Contains(header, keyword) && !bytes.Contains(body, keyword) {
+			return Match{Status: code, Rule: fmt.Sprintf("required keyword \"%s\" missing", keyword)}
+		}
+	}
+
+	if len(m.required) > 0 {
+		return Match{Hit: true, Status: code, Rule: fmt.Sprintf("status %d, required keywords present", code)}
+	}
+
+	return Match{Hit: true, Status: code, Rule: fmt.Sprintf("status %d, no ignore keyword", code)}
+}
@@ -20,3 +20,8 @@ func Base64URLDecode(raw string) (decS string, err error) {
 
 	return
 }
+
+func Base64URLEncode(raw string) string {
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GenerateCertificates writes a self-signed CA (ca.crt, ca.key) and the
+// node (node.crt, node.key) and client (client.crt, client.key) key pairs
+// issued by it into dir. hosts are the DNS names and IPs of the node.
+func GenerateCertificates(dir string, hosts []string) error {
+
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1"}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	caTemplate, err := newCertTemplate("r4scan CA", 10)
+	if err != nil {
+		return err
+	}
+
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	if err = writeCertificate(dir, "ca", caDer, caKey); err != nil {
+		return err
+	}
+
+	nodeTemplate, err := newCertTemplate("r4scan node", 2)
+	if err != nil {
+		return err
+	}
+
+	nodeTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			nodeTemplate.IPAddresses = append(nodeTemplate.IPAddresses, ip)
+		} else {
+			nodeTemplate.DNSNames = append(nodeTemplate.DNSNames, host)
+		}
+	}
+
+	if err = issueCertificate(dir, "node", nodeTemplate, caTemplate, caKey); err != nil {
+		return err
+	}
+
+	clientTemplate, err := newCertTemplate("r4scan client", 2)
+	if err != nil {
+		return err
+	}
+
+	clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	return issueCertificate(dir, "client", clientTemplate, caTemplate, caKey)
+}
+
+func newCertTemplate(commonName string, years int) (*x509.Certificate, error) {
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"r4scan"},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().AddDate(years, 0, 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}, nil
+}
+
+func issueCertificate(dir, name string, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) error {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return err
+	}
+
+	return writeCertificate(dir, name, der, key)
+}
+
+func writeCertificate(dir, name string, der []byte, key *ecdsa.PrivateKey) error {
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("write %s: %v", certFile, err)
+	}
+
+	keyFile := filepath.Join(dir, name+".key")
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return fmt.Errorf("write %s: %v", keyFile, err)
+	}
+
+	return nil
+}
+
+// LoadCertPool reads the pem certificates of file into a pool
+func LoadCertPool(file string) (*x509.CertPool, error) {
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in \"%s\"", file)
+	}
+
+	return pool, nil
+}
@@ -14,7 +14,7 @@ func GetAvailablePort(port ...int) (int, error) {
 		ports    int
 	)
 
-	if len(port) > 1 {
+	if len(port) > 0 {
 		ports = port[0]
 	}
 
@@ -12,18 +12,46 @@ import (
 //https://github.com/v2fly/vmessping
 
 type VmessLink struct {
-	Ver      string      `json:"-"`
-	Add      string      `json:"add"`
-	Aid      interface{} `json:"aid"`
-	Host     string      `json:"host"`
-	ID       string      `json:"id"`
-	Net      string      `json:"net"`
-	Path     string      `json:"path"`
-	Port     interface{} `json:"port"`
-	Ps       string      `json:"ps"`
-	TLS      string      `json:"tls"`
-	Type     string      `json:"type"`
-	OrigLink string      `json:"-"`
+	Ver                 string      `json:"-"`
+	Add                 string      `json:"add"`
+	Aid                 interface{} `json:"aid"`
+	Host                string      `json:"host"`
+	ID                  string      `json:"id"`
+	Net                 string      `json:"net"`
+	Path                string      `json:"path"`
+	Port                interface{} `json:"port"`
+	Ps                  string      `json:"ps"`
+	Scy                 string      `json:"scy"`
+	TLS                 string      `json:"tls"`
+	SNI                 string      `json:"sni"`
+	ALPN                string      `json:"alpn"`
+	Fp                  string      `json:"fp"`
+	AllowInsecure       interface{} `json:"allowInsecure"`
+	Experiments         string      `json:"experiments"`
+	Mux                 interface{} `json:"mux"`
+	MuxConcurrency      interface{} `json:"muxConcurrency"`
+	Header              []string    `json:"header"`
+	Method              string      `json:"method"`
+	MaxEarlyData        interface{} `json:"maxEarlyData"`
+	EarlyDataHeaderName string      `json:"earlyDataHeaderName"`
+	Type                string      `json:"type"`
+	OrigLink            string      `json:"-"`
+}
+
+// Insecure reports whether the link skips the certificate verification, the
+// field is written as a bool, a number or a string depending on the client
+func (v *VmessLink) Insecure() bool {
+
+	switch value := v.AllowInsecure.(type) {
+	case bool:
+		return value
+	case float64:
+		return value != 0
+	case string:
+		return value == "1" || strings.EqualFold(value, "true")
+	default:
+		return false
+	}
 }
 
 func newQuanVmess(vmess string) (*VmessLink, error) {
@@ -44,12 +72,16 @@ func newQuanVmess(vmess string) (*VmessLink, error) {
 
 	psn := strings.SplitN(info, " = ", 2)
 	if len(psn) != 2 {
-		return nil, fmt.Errorf("part error", info)
+		return nil, fmt.Errorf("part error: %s", info)
 	}
 	v.Ps = psn[0]
 	params := strings.Split(psn[1], ",")
+	if len(params) < 5 {
+		return nil, fmt.Errorf("part error: %s", info)
+	}
 	v.Add = params[1]
 	v.Port = params[2]
+	v.Scy = strings.TrimSpace(params[3])
 	v.ID = strings.ToLower(strings.Trim(params[4], "\""))
 	v.Aid = "0"
 	v.Net = "tcp"
@@ -57,11 +89,24 @@ func newQuanVmess(vmess string) (*VmessLink, error) {
 
 	if len(params) > 4 {
 		for _, pkv := range params[5:] {
-			kvp := strings.SplitN(pkv, "=", 2)
+			kvp := strings.SplitN(strings.TrimSpace(pkv), "=", 2)
+			if len(kvp) != 2 {
+				continue
+			}
+
 			if kvp[0] == "over-tls" && kvp[1] == "true" {
 				v.TLS = "tls"
 			}
 
+			if kvp[0] == "tls-host" {
+				v.SNI = kvp[1]
+			}
+
+			//quantumult verifies the certificate with certificate=1
+			if kvp[0] == "certificate" && kvp[1] == "0" {
+				v.AllowInsecure = true
+			}
+
 			if kvp[0] == "obfs" && kvp[1] == "ws" {
 				v.Net = "ws"
 			}
@@ -138,7 +183,7 @@ func newRkVmess(vmess string) (*VmessLink, error) {
 	if len(mhp) != 3 {
 		return nil, fmt.Errorf("vmess unreconized: method:host:port -- %v", mhp)
 	}
-	// mhp[0] is the encryption method
+	link.Scy = mhp[0]
 	link.Port = mhp[2]
 	idadd := strings.SplitN(mhp[1], "@", 2)
 	if len(idadd) != 2 {
@@ -170,6 +215,15 @@ func newRkVmess(vmess string) (*VmessLink, error) {
 	if v := vals.Get("obfsParam"); v != "" {
 		link.Host = v
 	}
+	if v := vals.Get("peer"); v != "" {
+		link.SNI = v
+	}
+	if v := vals.Get("alpn"); v != "" {
+		link.ALPN = v
+	}
+	if v := vals.Get("allowInsecure"); v != "" {
+		link.AllowInsecure = v
+	}
 
 	return link, nil
 }